@@ -0,0 +1,39 @@
+package main
+
+import "unicode/utf8"
+
+// anthropicCharsPerToken is the rough characters-per-token ratio Anthropic
+// publishes for Claude models on English-dominated text. It's a coarse
+// approximation, not a real tokenizer run: Anthropic doesn't ship a public
+// BPE vocabulary, so this trades precision for "close enough to budget
+// against" without a network round-trip per file.
+const anthropicCharsPerToken = 3.5
+
+// AnthropicTokenizer approximates Claude token counts from rune count
+// alone. Select it with -tokenizer=anthropic.
+type AnthropicTokenizer struct{}
+
+// NewAnthropicTokenizer creates a new AnthropicTokenizer.
+func NewAnthropicTokenizer() *AnthropicTokenizer {
+	return &AnthropicTokenizer{}
+}
+
+// Name identifies this tokenizer, as accepted by -tokenizer.
+func (at *AnthropicTokenizer) Name() string {
+	return "anthropic"
+}
+
+// CountTokens estimates the token count as rune count divided by the
+// average chars-per-token ratio, rounded up so short non-empty text never
+// counts as zero tokens.
+func (at *AnthropicTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	runes := utf8.RuneCountInString(text)
+	tokens := int(float64(runes)/anthropicCharsPerToken + 0.999999)
+	if tokens == 0 {
+		tokens = 1
+	}
+	return tokens
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// bpeEncoding describes where to fetch a named encoding's mergeable ranks
+// from, mirroring the layout OpenAI's tiktoken publishes.
+type bpeEncoding struct {
+	name       string
+	ranksURL   string
+	splitRegex string
+}
+
+// cl100kSplitPattern and o200kSplitPattern are the GPT-2-style
+// pre-tokenizer regexes used before BPE merging; o200k_base loosens a few
+// of cl100k_base's contraction/number rules.
+const (
+	cl100kSplitPattern = `'(?:[sdmt]|ll|ve|re)| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?:\S)|\s+`
+	o200kSplitPattern  = `'(?:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?:\S)|\s+`
+)
+
+var bpeEncodings = map[string]bpeEncoding{
+	"cl100k_base": {
+		name:       "cl100k_base",
+		ranksURL:   "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		splitRegex: cl100kSplitPattern,
+	},
+	"o200k_base": {
+		name:       "o200k_base",
+		ranksURL:   "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		splitRegex: o200kSplitPattern,
+	},
+}
+
+// BPETokenizer counts tokens by running the same byte-pair-encoding merge
+// algorithm as the named OpenAI encoding. Per-text results are cached by
+// content hash so repeated runs over an unchanged tree are cheap.
+type BPETokenizer struct {
+	encoding bpeEncoding
+	ranks    map[string]int
+	splitter *regexp.Regexp
+	cache    *tokenCache
+}
+
+// NewBPETokenizer loads the mergeable-ranks table for encoding (from a
+// local cache, downloading it on first use) and returns a ready-to-use
+// Tokenizer.
+func NewBPETokenizer(encodingName string) (*BPETokenizer, error) {
+	enc, ok := bpeEncodings[encodingName]
+	if !ok {
+		return nil, fmt.Errorf("unknown BPE encoding %q", encodingName)
+	}
+
+	ranks, err := loadMergeableRanks(enc)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s merges: %v", encodingName, err)
+	}
+
+	splitter, err := regexp.Compile(enc.splitRegex)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling %s pre-tokenizer pattern: %v", encodingName, err)
+	}
+
+	return &BPETokenizer{
+		encoding: enc,
+		ranks:    ranks,
+		splitter: splitter,
+		cache:    newTokenCache(encodingName),
+	}, nil
+}
+
+// Name identifies this tokenizer, as accepted by -tokenizer.
+func (bt *BPETokenizer) Name() string {
+	return bt.encoding.name
+}
+
+// Flush persists any token counts accumulated since the last flush to the
+// on-disk cache. Callers run this once after a run completes - see
+// flushTokenizerCache.
+func (bt *BPETokenizer) Flush() {
+	bt.cache.Flush()
+}
+
+// CountTokens pre-tokenizes text with the encoding's GPT-style regex, then
+// BPE-merges each piece, returning the total token count.
+func (bt *BPETokenizer) CountTokens(text string) int {
+	hash := hashText(text)
+	if cached, ok := bt.cache.Get(hash); ok {
+		return cached
+	}
+
+	total := 0
+	for _, piece := range bt.splitter.FindAllString(text, -1) {
+		total += len(bpeEncodePiece([]byte(piece), bt.ranks))
+	}
+
+	bt.cache.Set(hash, total)
+	return total
+}
+
+// mergeCandidate is one entry in the merge priority queue: the pair at
+// position pos (pos, pos+1) merges at the given rank.
+type mergeCandidate struct {
+	pos  int
+	rank int
+}
+
+// mergeQueue is a min-heap of mergeCandidate ordered by rank, so the
+// lowest-rank (highest-priority, per tiktoken's convention) pair is always
+// merged next.
+type mergeQueue []mergeCandidate
+
+func (q mergeQueue) Len() int            { return len(q) }
+func (q mergeQueue) Less(i, j int) bool  { return q[i].rank < q[j].rank }
+func (q mergeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *mergeQueue) Push(x interface{}) { *q = append(*q, x.(mergeCandidate)) }
+func (q *mergeQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// bpeEncodePiece merges byte-pairs of piece according to ranks, using a
+// priority queue keyed by merge rank, and returns the resulting token
+// byte-strings (their count is what callers care about). At each step it
+// looks up the concatenated bytes of the current adjacent parts directly
+// against ranks (the encoding's full vocab table), exactly as tiktoken's
+// reference bpe_encode does - not a pre-derived pair-split table, which
+// only records one (arbitrary) decomposition per merged token and can
+// miss the split the live merge order actually produces.
+func bpeEncodePiece(piece []byte, ranks map[string]int) [][]byte {
+	if len(piece) == 0 {
+		return nil
+	}
+	if len(piece) == 1 {
+		return [][]byte{piece}
+	}
+
+	parts := make([][]byte, len(piece))
+	for i, b := range piece {
+		parts[i] = []byte{b}
+	}
+
+	rankOf := func(pos int) (int, bool) {
+		if pos < 0 || pos+1 >= len(parts) {
+			return 0, false
+		}
+		key := string(parts[pos]) + string(parts[pos+1])
+		r, ok := ranks[key]
+		return r, ok
+	}
+
+	pq := &mergeQueue{}
+	heap.Init(pq)
+	for i := 0; i < len(parts)-1; i++ {
+		if r, ok := rankOf(i); ok {
+			heap.Push(pq, mergeCandidate{pos: i, rank: r})
+		}
+	}
+
+	for pq.Len() > 0 {
+		cand := heap.Pop(pq).(mergeCandidate)
+		if cand.pos+1 >= len(parts) {
+			continue // stale entry from before an earlier merge shrank parts
+		}
+		r, ok := rankOf(cand.pos)
+		if !ok || r != cand.rank {
+			continue // stale entry; the pair at this position has changed
+		}
+
+		merged := append(append([]byte{}, parts[cand.pos]...), parts[cand.pos+1]...)
+		parts = append(parts[:cand.pos], append([][]byte{merged}, parts[cand.pos+2:]...)...)
+
+		if r, ok := rankOf(cand.pos - 1); ok {
+			heap.Push(pq, mergeCandidate{pos: cand.pos - 1, rank: r})
+		}
+		if r, ok := rankOf(cand.pos); ok {
+			heap.Push(pq, mergeCandidate{pos: cand.pos, rank: r})
+		}
+	}
+
+	return parts
+}
+
+// loadMergeableRanks returns token->rank for enc, reading from the local
+// cache directory if present and downloading (then caching) otherwise.
+func loadMergeableRanks(enc bpeEncoding) (map[string]int, error) {
+	cacheDir, err := tokenizerCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(cacheDir, enc.name+".tiktoken")
+
+	f, err := os.Open(cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := downloadMergeableRanks(enc, cachePath); err != nil {
+			return nil, err
+		}
+		f, err = os.Open(cachePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer f.Close()
+
+	return parseMergeableRanks(f)
+}
+
+// downloadMergeableRanks fetches enc's .tiktoken file and writes it to
+// dest, so subsequent runs hit the cache instead of the network.
+func downloadMergeableRanks(enc bpeEncoding, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	resp, err := http.Get(enc.ranksURL)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %v", enc.ranksURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: status %s", enc.ranksURL, resp.Status)
+	}
+
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+// parseMergeableRanks reads tiktoken's "<base64 token> <rank>" format into
+// token-bytes -> rank, the encoding's full vocabulary. bpeEncodePiece looks
+// up each candidate merge directly against this table by concatenating the
+// adjacent parts' bytes, the same way tiktoken's reference encoder does.
+func parseMergeableRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		tokenBytes, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			continue
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		ranks[string(tokenBytes)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ranks, nil
+}
+
+// tokenizerCacheDir returns (creating if needed) the directory xdir caches
+// downloaded tokenizer merge tables in.
+func tokenizerCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	dir := filepath.Join(base, "xdir", "tokenizers")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// hashText returns a stable hex digest of text, used as the per-file token
+// cache key.
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
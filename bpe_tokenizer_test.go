@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestBpeEncodePieceFollowsLiveMergeOrder covers the case a pre-derived
+// pair-rank table gets wrong: "abc" is reachable via "a"+"bc" or "ab"+"c",
+// and only the live merge order (not a single stored split) picks the
+// right one.
+func TestBpeEncodePieceFollowsLiveMergeOrder(t *testing.T) {
+	ranks := map[string]int{
+		"a": 0, "b": 1, "c": 2,
+		"ab": 3, "bc": 4, "abc": 5,
+	}
+
+	got := bpeEncodePiece([]byte("abc"), ranks)
+	want := [][]byte{[]byte("abc")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bpeEncodePiece(%q) = %q, want %q", "abc", got, want)
+	}
+}
+
+func TestBpeEncodePieceNoMerges(t *testing.T) {
+	ranks := map[string]int{"a": 0, "b": 1}
+	got := bpeEncodePiece([]byte("ab"), ranks)
+	want := [][]byte{[]byte("a"), []byte("b")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bpeEncodePiece(%q) = %q, want %q", "ab", got, want)
+	}
+}
+
+func TestBpeEncodePieceEmptyAndSingle(t *testing.T) {
+	if got := bpeEncodePiece(nil, nil); got != nil {
+		t.Fatalf("bpeEncodePiece(nil) = %q, want nil", got)
+	}
+	got := bpeEncodePiece([]byte("x"), map[string]int{"x": 0})
+	want := [][]byte{[]byte("x")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bpeEncodePiece(%q) = %q, want %q", "x", got, want)
+	}
+}
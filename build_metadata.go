@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// BuildMetadata aggregates whatever build manifests were found at the
+// project root, so language analyzers can decide "local" vs "external"
+// from declared module/package identity rather than guessing off
+// filesystem prefixes.
+type BuildMetadata struct {
+	GoModule string // module path from go.mod, e.g. "github.com/foo/bar"
+
+	NPMName       string
+	NPMDeps       map[string]bool
+	NPMWorkspaces []string
+
+	PyProjectName string
+
+	CargoCrate string
+
+	JavaGroupID string
+
+	GemfileDeps map[string]bool
+
+	ComposerName string
+	ComposerDeps map[string]bool
+}
+
+// loadBuildMetadata looks for known manifest files directly under fsys's
+// root and parses whichever are present. Missing manifests simply leave
+// the corresponding fields zero-valued; analyzers fall back to their own
+// heuristics (e.g. a leading "." for JS relative imports) in that case.
+func loadBuildMetadata(fsys fs.FS) *BuildMetadata {
+	meta := &BuildMetadata{
+		NPMDeps:      make(map[string]bool),
+		GemfileDeps:  make(map[string]bool),
+		ComposerDeps: make(map[string]bool),
+	}
+
+	loadGoModule(fsys, meta)
+	loadPackageJSON(fsys, meta)
+	loadPyProject(fsys, meta)
+	loadCargoToml(fsys, meta)
+	loadJavaBuild(fsys, meta)
+	loadGemfile(fsys, meta)
+	loadComposerJSON(fsys, meta)
+
+	return meta
+}
+
+func loadGoModule(fsys fs.FS, meta *BuildMetadata) {
+	data, err := fs.ReadFile(fsys, "go.mod")
+	if err != nil {
+		return
+	}
+	modulePath := modfile.ModulePath(data)
+	meta.GoModule = modulePath
+}
+
+type packageJSON struct {
+	Name         string            `json:"name"`
+	Dependencies map[string]string `json:"dependencies"`
+	DevDeps      map[string]string `json:"devDependencies"`
+	Workspaces   json.RawMessage   `json:"workspaces"`
+}
+
+func loadPackageJSON(fsys fs.FS, meta *BuildMetadata) {
+	data, err := fs.ReadFile(fsys, "package.json")
+	if err != nil {
+		return
+	}
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return
+	}
+
+	meta.NPMName = pkg.Name
+	for dep := range pkg.Dependencies {
+		meta.NPMDeps[dep] = true
+	}
+	for dep := range pkg.DevDeps {
+		meta.NPMDeps[dep] = true
+	}
+
+	// "workspaces" is either a plain array or {"packages": [...]}.
+	var plain []string
+	if err := json.Unmarshal(pkg.Workspaces, &plain); err == nil {
+		meta.NPMWorkspaces = plain
+		return
+	}
+	var nested struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(pkg.Workspaces, &nested); err == nil {
+		meta.NPMWorkspaces = nested.Packages
+	}
+}
+
+var pyNameRegexp = regexp.MustCompile(`(?m)^\s*name\s*=\s*['"]([^'"]+)['"]`)
+
+func loadPyProject(fsys fs.FS, meta *BuildMetadata) {
+	for _, name := range []string{"pyproject.toml", "setup.cfg"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			continue
+		}
+		if m := pyNameRegexp.FindSubmatch(data); m != nil {
+			meta.PyProjectName = string(m[1])
+			return
+		}
+	}
+}
+
+var cargoNameRegexp = regexp.MustCompile(`(?m)^\s*name\s*=\s*"([^"]+)"`)
+
+func loadCargoToml(fsys fs.FS, meta *BuildMetadata) {
+	data, err := fs.ReadFile(fsys, "Cargo.toml")
+	if err != nil {
+		return
+	}
+	// The crate name is the first "name = ..." that appears before any
+	// [dependencies] table, i.e. inside [package].
+	if idx := strings.Index(string(data), "[dependencies"); idx >= 0 {
+		data = data[:idx]
+	}
+	if m := cargoNameRegexp.FindSubmatch(data); m != nil {
+		meta.CargoCrate = string(m[1])
+	}
+}
+
+var (
+	pomGroupIDRegexp  = regexp.MustCompile(`<groupId>([^<]+)</groupId>`)
+	gradleGroupRegexp = regexp.MustCompile(`(?m)^\s*group\s*=?\s*['"]([^'"]+)['"]`)
+)
+
+func loadJavaBuild(fsys fs.FS, meta *BuildMetadata) {
+	if data, err := fs.ReadFile(fsys, "pom.xml"); err == nil {
+		if m := pomGroupIDRegexp.FindSubmatch(data); m != nil {
+			meta.JavaGroupID = string(m[1])
+			return
+		}
+	}
+	for _, name := range []string{"build.gradle", "build.gradle.kts"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			continue
+		}
+		if m := gradleGroupRegexp.FindSubmatch(data); m != nil {
+			meta.JavaGroupID = string(m[1])
+			return
+		}
+	}
+}
+
+var gemRegexp = regexp.MustCompile(`(?m)^\s*gem\s+['"]([^'"]+)['"]`)
+
+func loadGemfile(fsys fs.FS, meta *BuildMetadata) {
+	data, err := fs.ReadFile(fsys, "Gemfile")
+	if err != nil {
+		return
+	}
+	for _, m := range gemRegexp.FindAllSubmatch(data, -1) {
+		meta.GemfileDeps[string(m[1])] = true
+	}
+}
+
+type composerJSON struct {
+	Name    string            `json:"name"`
+	Require map[string]string `json:"require"`
+}
+
+func loadComposerJSON(fsys fs.FS, meta *BuildMetadata) {
+	data, err := fs.ReadFile(fsys, "composer.json")
+	if err != nil {
+		return
+	}
+	var composer composerJSON
+	if err := json.Unmarshal(data, &composer); err != nil {
+		return
+	}
+	meta.ComposerName = composer.Name
+	for dep := range composer.Require {
+		meta.ComposerDeps[dep] = true
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadBuildMetadataParsesGoAndNPMManifests(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module github.com/acme/widget\n\ngo 1.21\n")},
+		"package.json": &fstest.MapFile{Data: []byte(`{
+			"name": "@acme/widget",
+			"dependencies": {"lodash": "^4.0.0"},
+			"workspaces": ["packages/*"]
+		}`)},
+	}
+
+	meta := loadBuildMetadata(fsys)
+
+	if meta.GoModule != "github.com/acme/widget" {
+		t.Errorf("GoModule = %q, want github.com/acme/widget", meta.GoModule)
+	}
+	if meta.NPMName != "@acme/widget" {
+		t.Errorf("NPMName = %q, want @acme/widget", meta.NPMName)
+	}
+	if !meta.NPMDeps["lodash"] {
+		t.Errorf("NPMDeps[lodash] = false, want true")
+	}
+	if len(meta.NPMWorkspaces) != 1 || meta.NPMWorkspaces[0] != "packages/*" {
+		t.Errorf("NPMWorkspaces = %v, want [packages/*]", meta.NPMWorkspaces)
+	}
+}
+
+func TestLoadBuildMetadataMissingManifestsLeavesZeroValues(t *testing.T) {
+	meta := loadBuildMetadata(fstest.MapFS{"main.go": &fstest.MapFile{Data: []byte("package main")}})
+	if meta.GoModule != "" || meta.NPMName != "" {
+		t.Errorf("expected zero-valued metadata with no manifests, got %+v", meta)
+	}
+}
+
+func TestClassifyJSImport(t *testing.T) {
+	meta := &BuildMetadata{
+		NPMName:       "@acme/widget",
+		NPMDeps:       map[string]bool{"lodash": true},
+		NPMWorkspaces: []string{"packages/*"},
+	}
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"./sibling", "local"},
+		{"../up", "local"},
+		{"@acme/widget/sub", "local"},
+		{"packages/other-pkg", "local"},
+		{"lodash", "external"},
+		{"@scope/unrelated", "external"},
+		{"fs", "standard"},
+		{"path", "standard"},
+	}
+	for _, c := range cases {
+		if got := classifyJSImport(c.path, meta); got != c.want {
+			t.Errorf("classifyJSImport(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestClassifyJSImportWithNoMetadata(t *testing.T) {
+	if got := classifyJSImport("./x", nil); got != "local" {
+		t.Errorf("classifyJSImport with nil meta: got %q, want local", got)
+	}
+	if got := classifyJSImport("express", nil); got != "standard" {
+		t.Errorf("classifyJSImport with nil meta: got %q, want standard", got)
+	}
+}
@@ -1,176 +1,72 @@
 package main
 
 import (
-	"bufio"
-	"go/parser"
-	"go/token"
-	"os"
-	"path/filepath"
-	"regexp"
+	"io/fs"
+	"path"
 	"strings"
 )
 
-// DependencyAnalyzer handles dependency analysis for different file types
+// DependencyAnalyzer handles dependency analysis for different file types.
+// All paths it deals in (fileMap keys, import targets) are fs.FS-style:
+// slash-separated, relative to fsys's root, no leading slash.
 type DependencyAnalyzer struct {
-	targetDir string
+	fsys      fs.FS
 	fileMap   map[string]*FileContent // maps file paths to their content objects
+	analyzers map[string]LanguageAnalyzer
+	meta      *BuildMetadata
 }
 
-// NewDependencyAnalyzer creates a new analyzer instance
-func NewDependencyAnalyzer(targetDir string) *DependencyAnalyzer {
+// NewDependencyAnalyzer creates a new analyzer instance over fsys.
+func NewDependencyAnalyzer(fsys fs.FS) *DependencyAnalyzer {
 	return &DependencyAnalyzer{
-		targetDir: targetDir,
+		fsys:      fsys,
 		fileMap:   make(map[string]*FileContent),
+		analyzers: buildAnalyzerRegistry(newLanguageAnalyzers()),
 	}
 }
 
 // RegisterFile adds a file to the dependency tracking system
-func (da *DependencyAnalyzer) RegisterFile(path string, content *FileContent) {
-	da.fileMap[path] = content
+func (da *DependencyAnalyzer) RegisterFile(relPath string, content *FileContent) {
+	da.fileMap[relPath] = content
 }
 
-// AnalyzeDependencies analyzes dependencies for all registered files
-func (da *DependencyAnalyzer) AnalyzeDependencies() error {
-	for path, content := range da.fileMap {
-		if err := da.analyzeFile(path, content); err != nil {
-			return err
-		}
-	}
-	return da.linkDependencies()
-}
-
-// analyzeFile determines the file type and calls the appropriate analyzer
-func (da *DependencyAnalyzer) analyzeFile(path string, content *FileContent) error {
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".go":
-		return da.analyzeGoFile(path, content)
-	case ".js", ".jsx", ".ts", ".tsx":
-		return da.analyzeJSFile(path, content)
-	case ".py":
-		return da.analyzePythonFile(path, content)
-	}
-	return nil
+// Lookup returns the FileContent registered for relPath, if any. Callers
+// use this to pick up the Dependencies AnalyzeDependencies attached
+// without having to keep their own copy of the file map.
+func (da *DependencyAnalyzer) Lookup(relPath string) (*FileContent, bool) {
+	content, ok := da.fileMap[relPath]
+	return content, ok
 }
 
-// analyzeGoFile analyzes dependencies in Go files
-func (da *DependencyAnalyzer) analyzeGoFile(path string, content *FileContent) error {
-	fset := token.NewFileSet()
-	f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
-	if err != nil {
-		return err
-	}
-
-	content.Dependencies = &DependencyInfo{
-		Imports: make([]ImportDependency, 0),
-	}
-
-	for _, imp := range f.Imports {
-		// Remove quotes from import path
-		importPath := strings.Trim(imp.Path.Value, "\"")
+// AnalyzeDependencies analyzes dependencies for all registered files.
+// Files whose content already has Dependencies attached are left alone -
+// watch mode's renderOnce relies on this to seed unchanged files with
+// their already-parsed content object, so the (re-opens and regex-scans
+// the file) analyzeFile pass only runs for files that actually changed.
+func (da *DependencyAnalyzer) AnalyzeDependencies() error {
+	da.meta = loadBuildMetadata(da.fsys)
 
-		depType := "standard"
-		if strings.Contains(importPath, ".") || strings.Contains(importPath, "/") {
-			depType = "external"
-		}
-		if strings.HasPrefix(importPath, da.targetDir) {
-			depType = "local"
+	for p, content := range da.fileMap {
+		if content.Dependencies != nil {
+			continue
 		}
-
-		content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
-			Path: importPath,
-			Type: depType,
-		})
-	}
-
-	return nil
-}
-
-// analyzeJSFile analyzes dependencies in JavaScript/TypeScript files
-func (da *DependencyAnalyzer) analyzeJSFile(path string, content *FileContent) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	content.Dependencies = &DependencyInfo{
-		Imports: make([]ImportDependency, 0),
-	}
-
-	// Regular expressions for different import patterns
-	importPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`import\s+.*\s+from\s+['"]([^'"]+)['"]`),
-		regexp.MustCompile(`require\(['"]([^'"]+)['"]\)`),
-		regexp.MustCompile(`import\s+['"]([^'"]+)['"]`),
-	}
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		for _, pattern := range importPatterns {
-			matches := pattern.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				importPath := matches[1]
-				depType := "external"
-				if strings.HasPrefix(importPath, ".") {
-					depType = "local"
-				} else if !strings.Contains(importPath, "/") {
-					depType = "standard"
-				}
-
-				content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
-					Path: importPath,
-					Type: depType,
-				})
-			}
+		if err := da.analyzeFile(p, content); err != nil {
+			return err
 		}
 	}
-
-	return scanner.Err()
+	return da.linkDependencies()
 }
 
-// analyzePythonFile analyzes dependencies in Python files
-func (da *DependencyAnalyzer) analyzePythonFile(path string, content *FileContent) error {
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+// analyzeFile dispatches to the LanguageAnalyzer registered for p's
+// extension, if any. Unrecognized extensions are left without
+// Dependencies, same as before this became pluggable.
+func (da *DependencyAnalyzer) analyzeFile(p string, content *FileContent) error {
+	ext := strings.ToLower(path.Ext(p))
+	analyzer, ok := da.analyzers[ext]
+	if !ok {
+		return nil
 	}
-	defer file.Close()
-
-	content.Dependencies = &DependencyInfo{
-		Imports: make([]ImportDependency, 0),
-	}
-
-	// Regular expressions for different import patterns
-	importPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`^import\s+(\w+)`),
-		regexp.MustCompile(`^from\s+([^\s]+)\s+import`),
-	}
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-
-		for _, pattern := range importPatterns {
-			matches := pattern.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				importPath := matches[1]
-				depType := "standard"
-				if strings.Contains(importPath, ".") {
-					depType = "local"
-				}
-
-				content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
-					Path: importPath,
-					Type: depType,
-				})
-			}
-		}
-	}
-
-	return scanner.Err()
+	return analyzer.Analyze(da.fsys, p, content, da.meta)
 }
 
 // linkDependencies creates bidirectional relationships between files
@@ -183,15 +79,16 @@ func (da *DependencyAnalyzer) linkDependencies() error {
 	}
 
 	// Build ImportedBy relationships
-	for path, content := range da.fileMap {
+	for p, content := range da.fileMap {
 		if content.Dependencies == nil {
 			continue
 		}
 
 		for _, imp := range content.Dependencies.Imports {
 			if imp.Type == "local" {
-				// Convert import path to filesystem path
-				importedPath := filepath.Join(da.targetDir, imp.Path)
+				// Resolve the import path relative to fsys's root, same as
+				// fileMap's keys.
+				importedPath := path.Clean(imp.Path)
 				if importedContent, exists := da.fileMap[importedPath]; exists {
 					if importedContent.Dependencies == nil {
 						importedContent.Dependencies = &DependencyInfo{
@@ -201,7 +98,7 @@ func (da *DependencyAnalyzer) linkDependencies() error {
 					importedContent.Dependencies.ImportedBy = append(
 						importedContent.Dependencies.ImportedBy,
 						ImportDependency{
-							Path: path,
+							Path: p,
 							Type: "local",
 						},
 					)
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Encoder renders FileContent entries into one of xdir's supported
+// output formats. StreamProcessor.Run asks the configured Encoder for a
+// header/footer to bracket the whole run and a separator to place
+// between consecutive files, and otherwise treats the format as opaque
+// bytes - adding a format means implementing this interface, not
+// touching the streaming pipeline.
+type Encoder interface {
+	// Name identifies this encoder, as accepted by -format.
+	Name() string
+	// DefaultExtension is used to pick an output filename when the user
+	// didn't override -format's implied default ("output.xml" etc).
+	DefaultExtension() string
+	// Header is written once, before the first file.
+	Header() []byte
+	// Footer is written once, after the last file.
+	Footer() []byte
+	// Separator is written between consecutive files, but not before the
+	// first or after the last.
+	Separator() []byte
+	// EncodeFile renders a single file's content and dependency info.
+	EncodeFile(content *FileContent) ([]byte, error)
+}
+
+// NewEncoder selects an Encoder implementation by name, as surfaced via
+// -format on the CLI. An empty name defaults to "xml", xdir's original
+// format.
+func NewEncoder(name string) (Encoder, error) {
+	switch name {
+	case "", "xml":
+		return &XMLEncoder{}, nil
+	case "json":
+		return &JSONEncoder{}, nil
+	case "jsonl":
+		return &JSONLEncoder{}, nil
+	case "md", "markdown":
+		return &MarkdownEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want xml, json, jsonl, or md)", name)
+	}
+}
+
+// XMLEncoder is xdir's original format: a <files> document containing one
+// <file> element per entry.
+type XMLEncoder struct{}
+
+func (e *XMLEncoder) Name() string             { return "xml" }
+func (e *XMLEncoder) DefaultExtension() string { return ".xml" }
+func (e *XMLEncoder) Header() []byte           { return []byte(xml.Header + "<files>\n") }
+func (e *XMLEncoder) Footer() []byte           { return []byte("</files>\n") }
+func (e *XMLEncoder) Separator() []byte        { return nil }
+
+func (e *XMLEncoder) EncodeFile(content *FileContent) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := xml.NewEncoder(&out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(content); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// jsonFile mirrors FileContent with JSON-friendly tags, since FileContent
+// itself is tagged for xml.
+type jsonFile struct {
+	Name         string          `json:"name"`
+	Size         int64           `json:"size"`
+	Tokens       int64           `json:"tokens,omitempty"`
+	Content      string          `json:"content"`
+	Dependencies *DependencyInfo `json:"dependencies,omitempty"`
+}
+
+func toJSONFile(content *FileContent) jsonFile {
+	return jsonFile{
+		Name:         content.Name,
+		Size:         content.Size,
+		Tokens:       content.Tokens,
+		Content:      content.Content,
+		Dependencies: content.Dependencies,
+	}
+}
+
+// JSONEncoder emits a single top-level JSON array, one object per file -
+// convenient for tooling that wants to json.Unmarshal the whole tree at
+// once.
+type JSONEncoder struct{}
+
+func (e *JSONEncoder) Name() string             { return "json" }
+func (e *JSONEncoder) DefaultExtension() string { return ".json" }
+func (e *JSONEncoder) Header() []byte           { return []byte("[\n") }
+func (e *JSONEncoder) Footer() []byte           { return []byte("\n]\n") }
+func (e *JSONEncoder) Separator() []byte        { return []byte(",\n") }
+
+func (e *JSONEncoder) EncodeFile(content *FileContent) ([]byte, error) {
+	return json.MarshalIndent(toJSONFile(content), "", "  ")
+}
+
+// JSONLEncoder emits one JSON object per line with no enclosing array,
+// so embedding pipelines and RAG loaders can stream-parse it line by
+// line without holding the whole document in memory.
+type JSONLEncoder struct{}
+
+func (e *JSONLEncoder) Name() string             { return "jsonl" }
+func (e *JSONLEncoder) DefaultExtension() string { return ".jsonl" }
+func (e *JSONLEncoder) Header() []byte           { return nil }
+func (e *JSONLEncoder) Footer() []byte           { return nil }
+func (e *JSONLEncoder) Separator() []byte        { return nil }
+
+func (e *JSONLEncoder) EncodeFile(content *FileContent) ([]byte, error) {
+	data, err := json.Marshal(toJSONFile(content))
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// MarkdownEncoder emits a fenced-code-block per file, the layout most
+// chat UIs paste and render well.
+type MarkdownEncoder struct{}
+
+func (e *MarkdownEncoder) Name() string             { return "md" }
+func (e *MarkdownEncoder) DefaultExtension() string { return ".md" }
+func (e *MarkdownEncoder) Header() []byte           { return []byte("# Directory export\n\n") }
+func (e *MarkdownEncoder) Footer() []byte           { return nil }
+func (e *MarkdownEncoder) Separator() []byte        { return nil }
+
+func (e *MarkdownEncoder) EncodeFile(content *FileContent) ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "## %s\n\n", content.Name)
+
+	fence := fenceFor(content.Content)
+	fmt.Fprintf(&out, "%s%s\n", fence, markdownLang(content.Name))
+	out.WriteString(content.Content)
+	if !strings.HasSuffix(content.Content, "\n") {
+		out.WriteByte('\n')
+	}
+	out.WriteString(fence)
+	out.WriteString("\n\n")
+	return out.Bytes(), nil
+}
+
+// fenceFor returns a backtick fence long enough that it can't be closed by
+// any backtick run already inside content, as most Markdown tools do:
+// max(3, longest run of backticks in content) + 1.
+func fenceFor(content string) string {
+	longest := 0
+	run := 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	length := longest + 1
+	if length < 3 {
+		length = 3
+	}
+	return strings.Repeat("`", length)
+}
+
+// markdownLangByExt maps common file extensions to the fenced-code-block
+// language tag chat UIs recognize for syntax highlighting.
+var markdownLangByExt = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "jsx",
+	".ts": "typescript", ".tsx": "tsx", ".rb": "ruby", ".rs": "rust",
+	".java": "java", ".php": "php", ".c": "c", ".h": "c", ".cpp": "cpp",
+	".hpp": "cpp", ".json": "json", ".yaml": "yaml", ".yml": "yaml",
+	".md": "markdown", ".sql": "sql", ".sh": "bash", ".html": "html",
+	".css": "css", ".xml": "xml",
+}
+
+func markdownLang(name string) string {
+	return markdownLangByExt[strings.ToLower(filepath.Ext(name))]
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMarkdownEncoderEscapesEmbeddedFences covers content that itself
+// contains a triple-backtick block (e.g. a README with a fenced code
+// sample): the outer fence must be long enough that the embedded one
+// can't be mistaken for its close.
+func TestMarkdownEncoderEscapesEmbeddedFences(t *testing.T) {
+	e := &MarkdownEncoder{}
+	content := &FileContent{
+		Name:    "README.md",
+		Content: "See below:\n\n```go\nfmt.Println(\"hi\")\n```\n",
+	}
+
+	data, err := e.EncodeFile(content)
+	if err != nil {
+		t.Fatalf("EncodeFile: %v", err)
+	}
+	out := string(data)
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	var fenceLines int
+	for _, l := range lines {
+		if strings.HasPrefix(l, "````") {
+			fenceLines++
+		}
+	}
+	if fenceLines != 2 {
+		t.Fatalf("expected exactly 2 outer-fence lines, got %d in:\n%s", fenceLines, out)
+	}
+}
+
+func TestFenceForPlainContent(t *testing.T) {
+	if got := fenceFor("no backticks here"); got != "```" {
+		t.Fatalf("fenceFor(plain) = %q, want ```", got)
+	}
+}
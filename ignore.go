@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// ignoreFileNames are the ignore files consulted at every directory level,
+// in addition to the extra file supplied via -ignore-file.
+var ignoreFileNames = []string{".gitignore", ".xdirignore"}
+
+// IgnoreRule is a single compiled pattern loaded from an ignore file.
+type IgnoreRule struct {
+	pattern string // pattern relative to base, doublestar syntax
+	negate  bool   // pattern was prefixed with "!"
+	dirOnly bool   // pattern was suffixed with "/"
+}
+
+// ignoreLayer holds the rules contributed by a single directory (or the
+// synthetic default/extra layers loaded once at startup). dir is a
+// fs.FS-style path ("." for the source root, slash-separated below that).
+type ignoreLayer struct {
+	dir   string
+	rules []IgnoreRule
+}
+
+// Ignorer implements layered, gitignore-compatible path exclusion over an
+// arbitrary fs.FS (a local directory, an archive, or a git tree). Rules
+// from nested ignore files are pushed/popped as the walker descends and
+// ascends the tree, mirroring how git itself resolves exclusions.
+type Ignorer struct {
+	fsys   fs.FS
+	stack  []ignoreLayer
+	loaded map[string]bool // fs.FS directories whose ignore files have already been pushed
+	noGit  bool
+}
+
+// NewIgnorer builds an Ignorer over fsys. It loads the source root's
+// .gitignore/.xdirignore (unless noGitignore is set) plus an optional
+// extra ignore file from the host filesystem, and seeds a synthetic layer
+// with xdir's built-in defaults so behavior without any ignore files is
+// unsurprising.
+func NewIgnorer(fsys fs.FS, extraIgnoreFile string, noGitignore bool) (*Ignorer, error) {
+	ig := &Ignorer{
+		fsys:   fsys,
+		loaded: make(map[string]bool),
+		noGit:  noGitignore,
+	}
+
+	defaults := make([]IgnoreRule, 0, len(ExcludedPaths))
+	for _, p := range ExcludedPaths {
+		defaults = append(defaults, IgnoreRule{pattern: p})
+	}
+	ig.stack = append(ig.stack, ignoreLayer{dir: ".", rules: defaults})
+
+	if extraIgnoreFile != "" {
+		rules, err := parseHostIgnoreFile(extraIgnoreFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading -ignore-file %s: %v", extraIgnoreFile, err)
+		}
+		ig.stack = append(ig.stack, ignoreLayer{dir: ".", rules: rules})
+	}
+
+	if err := ig.Enter("."); err != nil {
+		return nil, err
+	}
+
+	return ig, nil
+}
+
+// parseIgnoreFile reads a gitignore-style file at relPath within fsys into
+// a slice of IgnoreRule. A missing file yields no rules, not an error,
+// since most directories don't have one.
+func parseIgnoreFile(fsys fs.FS, relPath string) ([]IgnoreRule, error) {
+	f, err := fsys.Open(relPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return scanIgnoreRules(f)
+}
+
+// parseHostIgnoreFile reads an ignore file directly off the host
+// filesystem. It's used for -ignore-file, which names a real path on disk
+// regardless of what fs.FS the target content is coming from (an archive
+// or git ref, say).
+func parseHostIgnoreFile(hostPath string) ([]IgnoreRule, error) {
+	f, err := os.Open(hostPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return scanIgnoreRules(f)
+}
+
+func scanIgnoreRules(f io.Reader) ([]IgnoreRule, error) {
+	var rules []IgnoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		line = strings.TrimRight(line, " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := IgnoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+// Enter adjusts the layer stack so it matches dir (an fs.FS-style path,
+// "." for the source root): layers whose directory is no longer an
+// ancestor of dir are popped, and any ignore files between the deepest
+// surviving ancestor and dir - including the source root's own, the
+// first time Enter is called - are loaded and pushed. It is safe to call
+// repeatedly as the walk descends and ascends.
+func (ig *Ignorer) Enter(dir string) error {
+	dir = path.Clean(dir)
+
+	for len(ig.stack) > 0 {
+		top := ig.stack[len(ig.stack)-1]
+		if top.dir == "." || isAncestorDir(top.dir, dir) {
+			break
+		}
+		ig.stack = ig.stack[:len(ig.stack)-1]
+	}
+
+	var parts []string
+	if dir != "." {
+		parts = strings.Split(dir, "/")
+	}
+
+	cur := "."
+	if err := ig.loadLayerFiles(cur); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		cur = path.Join(cur, part)
+		if err := ig.loadLayerFiles(cur); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadLayerFiles loads cur's ignore files (if not already loaded) and
+// pushes a new layer for them if any rules were found.
+func (ig *Ignorer) loadLayerFiles(cur string) error {
+	if ig.loaded[cur] {
+		return nil
+	}
+	ig.loaded[cur] = true
+
+	if ig.noGit {
+		return nil
+	}
+	for _, name := range ignoreFileNames {
+		rules, err := parseIgnoreFile(ig.fsys, path.Join(cur, name))
+		if err != nil {
+			return fmt.Errorf("error loading %s in %s: %v", name, cur, err)
+		}
+		if len(rules) > 0 {
+			ig.stack = append(ig.stack, ignoreLayer{dir: cur, rules: rules})
+		}
+	}
+	return nil
+}
+
+// Match reports whether p (a file or directory, as an fs.FS-style path)
+// is ignored, resolving negations and nested-layer precedence the same
+// way git does: later layers (deeper directories) and later rules within
+// a layer win.
+func (ig *Ignorer) Match(p string, isDir bool) bool {
+	p = path.Clean(p)
+	ignored := false
+
+	for _, layer := range ig.stack {
+		relLayer, ok := relUnder(layer.dir, p)
+		if !ok {
+			continue
+		}
+
+		for _, rule := range layer.rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if matchIgnorePattern(rule.pattern, relLayer) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	return ignored
+}
+
+// matchIgnorePattern matches a single gitignore-style pattern (which may
+// contain "**") against a slash-separated path relative to the ignore
+// file's directory.
+func matchIgnorePattern(pattern, relPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		// A pattern with no slash matches at any depth, like git's
+		// "foo" matching "foo" and "bar/foo".
+		if ok, _ := doublestar.Match(pattern, path.Base(relPath)); ok {
+			return true
+		}
+		pattern = "**/" + pattern
+	} else if strings.HasPrefix(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+
+	ok, _ := doublestar.Match(pattern, relPath)
+	return ok
+}
+
+// isAncestorDir reports whether ancestor is dir itself or a parent of dir,
+// both given as fs.FS-style ("." for root, "/"-separated) paths.
+func isAncestorDir(ancestor, dir string) bool {
+	_, ok := relUnder(ancestor, dir)
+	return ok
+}
+
+// relUnder returns dir's path relative to base (both fs.FS-style paths)
+// and whether dir actually lives under base at all.
+func relUnder(base, dir string) (string, bool) {
+	if base == "." {
+		return dir, true
+	}
+	if dir == base {
+		return ".", true
+	}
+	if strings.HasPrefix(dir, base+"/") {
+		return strings.TrimPrefix(dir, base+"/"), true
+	}
+	return "", false
+}
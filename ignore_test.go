@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestIgnorerMatchesNestedAndNegatedPatterns(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":     &fstest.MapFile{Data: []byte("*.log\n!keep.log\nbuild/\n")},
+		"a.log":          &fstest.MapFile{Data: []byte("x")},
+		"keep.log":       &fstest.MapFile{Data: []byte("x")},
+		"build":          &fstest.MapFile{Mode: 0o755},
+		"src/nested.log": &fstest.MapFile{Data: []byte("x")},
+		"src/.gitignore": &fstest.MapFile{Data: []byte("!nested.log\n")},
+		"src/README.md":  &fstest.MapFile{Data: []byte("x")},
+	}
+
+	ig, err := NewIgnorer(fsys, "", false)
+	if err != nil {
+		t.Fatalf("NewIgnorer: %v", err)
+	}
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/README.md", false, false},
+	}
+	for _, c := range cases {
+		if got := ig.Match(c.path, c.isDir); got != c.want {
+			t.Errorf("Match(%q, isDir=%v) = %v, want %v", c.path, c.isDir, got, c.want)
+		}
+	}
+
+	// A deeper .gitignore re-negates *.log for its own subtree: entering
+	// "src" before matching should flip nested.log back to included.
+	if err := ig.Enter("src"); err != nil {
+		t.Fatalf("Enter(src): %v", err)
+	}
+	if got := ig.Match("src/nested.log", false); got {
+		t.Errorf(`Match("src/nested.log") = true, want false (re-negated by src/.gitignore)`)
+	}
+}
+
+func TestIgnorerDefaultsExcludeGitAndNodeModules(t *testing.T) {
+	fsys := fstest.MapFS{
+		".git/HEAD":               &fstest.MapFile{Data: []byte("x")},
+		"node_modules/pkg/idx.js": &fstest.MapFile{Data: []byte("x")},
+		"main.go":                 &fstest.MapFile{Data: []byte("x")},
+	}
+	ig, err := NewIgnorer(fsys, "", false)
+	if err != nil {
+		t.Fatalf("NewIgnorer: %v", err)
+	}
+
+	if !ig.Match(".git", true) {
+		t.Error(`Match(".git") = false, want true (built-in default)`)
+	}
+	if !ig.Match("node_modules", true) {
+		t.Error(`Match("node_modules") = false, want true (built-in default)`)
+	}
+	if ig.Match("main.go", false) {
+		t.Error(`Match("main.go") = true, want false`)
+	}
+}
+
+func TestIgnorerNoGitignoreSkipsTreeFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte("*.log\n")},
+		"a.log":      &fstest.MapFile{Data: []byte("x")},
+	}
+	ig, err := NewIgnorer(fsys, "", true)
+	if err != nil {
+		t.Fatalf("NewIgnorer: %v", err)
+	}
+	if ig.Match("a.log", false) {
+		t.Error(`Match("a.log") = true with -no-gitignore, want false`)
+	}
+}
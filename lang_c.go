@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// CFamilyAnalyzer parses C/C++ #include directives. Dependency type is
+// decided purely by include syntax, since there's no project manifest
+// convention (CMakeLists.txt, Makefile) consistent enough to trust for
+// "local" detection: "foo.h" is local, <foo.h> is external/standard.
+type CFamilyAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *CFamilyAnalyzer) Extensions() []string {
+	return []string{".c", ".h", ".cpp", ".hpp", ".cc", ".cxx"}
+}
+
+var (
+	cIncludeQuotedRegexp = regexp.MustCompile(`^#include\s+"([^"]+)"`)
+	cIncludeAngleRegexp  = regexp.MustCompile(`^#include\s+<([^>]+)>`)
+)
+
+// cStandardHeaders lists common C/C++ standard library headers, used to
+// distinguish "standard" from "external" among angle-bracket includes.
+var cStandardHeaders = map[string]bool{
+	"stdio.h": true, "stdlib.h": true, "string.h": true, "stdint.h": true,
+	"stdbool.h": true, "math.h": true, "assert.h": true, "errno.h": true,
+	"time.h": true, "ctype.h": true, "limits.h": true,
+	"iostream": true, "vector": true, "string": true, "map": true,
+	"memory": true, "algorithm": true, "functional": true, "thread": true,
+	"mutex": true, "optional": true, "variant": true, "array": true,
+}
+
+// Analyze implements LanguageAnalyzer.
+func (a *CFamilyAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := cIncludeQuotedRegexp.FindStringSubmatch(line); m != nil {
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: "local",
+			})
+			continue
+		}
+
+		if m := cIncludeAngleRegexp.FindStringSubmatch(line); m != nil {
+			depType := "external"
+			if cStandardHeaders[m[1]] {
+				depType = "standard"
+			}
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: depType,
+			})
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"strings"
+)
+
+// GoAnalyzer parses Go source files with go/parser and classifies each
+// import against the module path declared in the project's go.mod.
+type GoAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *GoAnalyzer) Extensions() []string { return []string{".go"} }
+
+// Analyze implements LanguageAnalyzer.
+func (a *GoAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	src, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	for _, imp := range f.Imports {
+		importPath := strings.Trim(imp.Path.Value, "\"")
+		content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+			Path: importPath,
+			Type: classifyGoImport(importPath, meta),
+		})
+	}
+
+	return nil
+}
+
+// classifyGoImport decides standard/local/external for a Go import path.
+// "Local" now means "under this module's path", as declared by go.mod,
+// rather than merely containing a dot or slash - that misclassified
+// every external dependency (e.g. github.com/...) as local before.
+func classifyGoImport(importPath string, meta *BuildMetadata) string {
+	if meta != nil && meta.GoModule != "" {
+		if importPath == meta.GoModule || strings.HasPrefix(importPath, meta.GoModule+"/") {
+			return "local"
+		}
+	}
+
+	if !strings.Contains(importPath, ".") && !strings.Contains(importPath, "/") {
+		return "standard"
+	}
+	// Import paths with a dotted first segment (e.g. golang.org/x/mod,
+	// github.com/foo/bar) are stdlib-shaped only for a handful of
+	// sub-repos; anything else with a slash but no module match is a
+	// third-party dependency.
+	return "external"
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// JavaAnalyzer parses Java import statements and classifies them using
+// the project's Maven/Gradle group ID.
+type JavaAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *JavaAnalyzer) Extensions() []string { return []string{".java"} }
+
+var javaImportRegexp = regexp.MustCompile(`^import\s+(?:static\s+)?([A-Za-z0-9_.]+)(?:\.\*)?\s*;`)
+
+// Analyze implements LanguageAnalyzer.
+func (a *JavaAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		m := javaImportRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		importPath := m[1]
+		content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+			Path: importPath,
+			Type: classifyJavaImport(importPath, meta),
+		})
+	}
+
+	return scanner.Err()
+}
+
+// classifyJavaImport decides standard/local/external for a fully
+// qualified Java import: java.*/javax.* is standard, anything under the
+// project's own group ID is local, everything else is external.
+func classifyJavaImport(importPath string, meta *BuildMetadata) string {
+	if strings.HasPrefix(importPath, "java.") || strings.HasPrefix(importPath, "javax.") {
+		return "standard"
+	}
+	if meta != nil && meta.JavaGroupID != "" && strings.HasPrefix(importPath, meta.JavaGroupID) {
+		return "local"
+	}
+	return "external"
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// JSAnalyzer parses JavaScript/TypeScript import and require statements
+// and classifies them using the project's package.json.
+type JSAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *JSAnalyzer) Extensions() []string {
+	return []string{".js", ".jsx", ".ts", ".tsx"}
+}
+
+var jsImportPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`import\s+.*\s+from\s+['"]([^'"]+)['"]`),
+	regexp.MustCompile(`require\(['"]([^'"]+)['"]\)`),
+	regexp.MustCompile(`import\s+['"]([^'"]+)['"]`),
+}
+
+// Analyze implements LanguageAnalyzer.
+func (a *JSAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, pattern := range jsImportPatterns {
+			matches := pattern.FindStringSubmatch(line)
+			if len(matches) > 1 {
+				importPath := matches[1]
+				content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+					Path: importPath,
+					Type: classifyJSImport(importPath, meta),
+				})
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// classifyJSImport decides standard/local/external for a JS/TS import
+// specifier. Relative paths are always local; a bare specifier is local
+// if it matches the package.json "name" or a declared workspace package,
+// external if it's a declared dependency or scoped (@scope/pkg), and
+// standard otherwise (Node builtins like "fs" or "path").
+func classifyJSImport(importPath string, meta *BuildMetadata) string {
+	if strings.HasPrefix(importPath, ".") {
+		return "local"
+	}
+
+	if meta != nil {
+		if meta.NPMName != "" && (importPath == meta.NPMName || strings.HasPrefix(importPath, meta.NPMName+"/")) {
+			return "local"
+		}
+		for _, ws := range meta.NPMWorkspaces {
+			if strings.HasPrefix(importPath, strings.TrimSuffix(ws, "/*")) {
+				return "local"
+			}
+		}
+		if meta.NPMDeps[importPath] {
+			return "external"
+		}
+	}
+
+	if strings.HasPrefix(importPath, "@") || strings.Contains(importPath, "/") {
+		return "external"
+	}
+
+	return "standard"
+}
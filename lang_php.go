@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// PHPAnalyzer parses PHP "use" statements and classifies them using the
+// project's composer.json package name.
+type PHPAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *PHPAnalyzer) Extensions() []string { return []string{".php"} }
+
+var phpUseRegexp = regexp.MustCompile(`^use\s+([A-Za-z0-9_\\]+)`)
+
+// Analyze implements LanguageAnalyzer.
+func (a *PHPAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		m := phpUseRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		importPath := strings.TrimSuffix(m[1], ";")
+		content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+			Path: importPath,
+			Type: classifyPHPUse(importPath, meta),
+		})
+	}
+
+	return scanner.Err()
+}
+
+// classifyPHPUse decides local/external for a namespaced PHP "use": a
+// namespace under the composer package's own vendor/name (mapped to its
+// PSR-4 namespace prefix) is local, a namespace matching a required
+// package's vendor is external, and everything else falls back to
+// external since PHP has no single canonical standard-library namespace.
+func classifyPHPUse(namespace string, meta *BuildMetadata) string {
+	if meta == nil {
+		return "external"
+	}
+
+	if meta.ComposerName != "" {
+		if prefix := composerNamespacePrefix(meta.ComposerName); prefix != "" && strings.HasPrefix(namespace, prefix) {
+			return "local"
+		}
+	}
+
+	for dep := range meta.ComposerDeps {
+		if prefix := composerNamespacePrefix(dep); prefix != "" && strings.HasPrefix(namespace, prefix) {
+			return "external"
+		}
+	}
+
+	return "external"
+}
+
+// composerNamespacePrefix turns a "vendor/package" composer name into the
+// studly-cased namespace prefix convention PSR-4 autoloading uses, e.g.
+// "my-vendor/my-package" -> "MyVendor\MyPackage".
+func composerNamespacePrefix(composerName string) string {
+	parts := strings.Split(composerName, "/")
+	for i, part := range parts {
+		var studly strings.Builder
+		for _, word := range strings.Split(part, "-") {
+			if word == "" {
+				continue
+			}
+			studly.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+		parts[i] = studly.String()
+	}
+	return strings.Join(parts, `\`)
+}
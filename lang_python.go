@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// PythonAnalyzer parses Python import statements and classifies them
+// using the project's pyproject.toml/setup.cfg package name.
+type PythonAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *PythonAnalyzer) Extensions() []string { return []string{".py"} }
+
+var pyImportPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^import\s+(\w+)`),
+	regexp.MustCompile(`^from\s+([^\s]+)\s+import`),
+}
+
+// Analyze implements LanguageAnalyzer.
+func (a *PythonAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		for _, pattern := range pyImportPatterns {
+			matches := pattern.FindStringSubmatch(line)
+			if len(matches) > 1 {
+				importPath := matches[1]
+				content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+					Path: importPath,
+					Type: classifyPythonImport(importPath, meta),
+				})
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// classifyPythonImport decides standard/local/external for a Python
+// import. Relative imports (leading dots) and imports rooted at the
+// project's own package name are local; anything else is treated as
+// external, since distinguishing stdlib from PyPI packages reliably
+// would need the interpreter's own sys.stdlib_module_names.
+func classifyPythonImport(importPath string, meta *BuildMetadata) string {
+	if strings.HasPrefix(importPath, ".") {
+		return "local"
+	}
+
+	root := strings.SplitN(importPath, ".", 2)[0]
+	if meta != nil && meta.PyProjectName != "" && root == normalizePyName(meta.PyProjectName) {
+		return "local"
+	}
+
+	return "external"
+}
+
+// normalizePyName approximates PEP 503 name normalization (packages are
+// commonly importable under their name with hyphens turned to
+// underscores).
+func normalizePyName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// RubyAnalyzer parses Ruby require/require_relative statements and
+// classifies them using the project's Gemfile.
+type RubyAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *RubyAnalyzer) Extensions() []string { return []string{".rb"} }
+
+var (
+	rubyRequireRegexp         = regexp.MustCompile(`^require\s+['"]([^'"]+)['"]`)
+	rubyRequireRelativeRegexp = regexp.MustCompile(`^require_relative\s+['"]([^'"]+)['"]`)
+)
+
+// Analyze implements LanguageAnalyzer.
+func (a *RubyAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := rubyRequireRelativeRegexp.FindStringSubmatch(line); m != nil {
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: "local",
+			})
+			continue
+		}
+
+		if m := rubyRequireRegexp.FindStringSubmatch(line); m != nil {
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: classifyRubyRequire(m[1], meta),
+			})
+		}
+	}
+
+	return scanner.Err()
+}
+
+// classifyRubyRequire decides local/external for a require path; Ruby has
+// no enumerable standard-library manifest to check against, so anything
+// not declared in the Gemfile is treated as external/stdlib alike.
+func classifyRubyRequire(requirePath string, meta *BuildMetadata) string {
+	gemName := strings.SplitN(requirePath, "/", 2)[0]
+	if meta != nil && meta.GemfileDeps[gemName] {
+		return "external"
+	}
+	return "standard"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// RustAnalyzer parses Rust use/mod/extern crate declarations and
+// classifies them using the project's Cargo.toml crate name.
+type RustAnalyzer struct{}
+
+// Extensions implements LanguageAnalyzer.
+func (a *RustAnalyzer) Extensions() []string { return []string{".rs"} }
+
+var (
+	rustUseRegexp    = regexp.MustCompile(`^use\s+([A-Za-z0-9_:]+)`)
+	rustExternRegexp = regexp.MustCompile(`^extern\s+crate\s+([A-Za-z0-9_]+)`)
+	rustModRegexp    = regexp.MustCompile(`^mod\s+([A-Za-z0-9_]+)\s*;`)
+)
+
+// Analyze implements LanguageAnalyzer.
+func (a *RustAnalyzer) Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	content.Dependencies = &DependencyInfo{
+		Imports: make([]ImportDependency, 0),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := rustModRegexp.FindStringSubmatch(line); m != nil {
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: "local",
+			})
+			continue
+		}
+
+		if m := rustExternRegexp.FindStringSubmatch(line); m != nil {
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: classifyRustCrate(m[1], meta),
+			})
+			continue
+		}
+
+		if m := rustUseRegexp.FindStringSubmatch(line); m != nil {
+			crate := strings.SplitN(m[1], "::", 2)[0]
+			content.Dependencies.Imports = append(content.Dependencies.Imports, ImportDependency{
+				Path: m[1],
+				Type: classifyRustCrate(crate, meta),
+			})
+		}
+	}
+
+	return scanner.Err()
+}
+
+// classifyRustCrate decides standard/local/external for a crate root
+// named in a use/extern crate statement.
+func classifyRustCrate(crate string, meta *BuildMetadata) string {
+	switch crate {
+	case "self", "super", "crate":
+		return "local"
+	case "std", "core", "alloc", "proc_macro", "test":
+		return "standard"
+	}
+	if meta != nil && meta.CargoCrate != "" && crate == strings.ReplaceAll(meta.CargoCrate, "-", "_") {
+		return "local"
+	}
+	return "external"
+}
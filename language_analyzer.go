@@ -0,0 +1,50 @@
+package main
+
+import "io/fs"
+
+// LanguageAnalyzer parses a single file's import/require/use statements
+// and classifies each as "local" (part of this project), "external" (a
+// third-party dependency), or "standard" (part of the language's
+// standard library). Adding a new language means implementing this
+// interface and registering it in newLanguageAnalyzers - the dispatch in
+// DependencyAnalyzer.analyzeFile never needs to change.
+type LanguageAnalyzer interface {
+	// Extensions lists the lowercase, dot-prefixed file extensions this
+	// analyzer handles, e.g. []string{".rs"}.
+	Extensions() []string
+	// Analyze reads path out of fsys, populates content.Dependencies.Imports,
+	// and classifies each import using meta (the project's parsed build
+	// manifests - go.mod, package.json, Cargo.toml, and so on). Reading
+	// through fsys rather than the host filesystem directly is what lets
+	// dependency analysis run the same way over a local directory, an
+	// archive, or a git ref.
+	Analyze(fsys fs.FS, path string, content *FileContent, meta *BuildMetadata) error
+}
+
+// newLanguageAnalyzers returns one instance of every supported
+// LanguageAnalyzer. Registering a new language is a one-line addition
+// here.
+func newLanguageAnalyzers() []LanguageAnalyzer {
+	return []LanguageAnalyzer{
+		&GoAnalyzer{},
+		&JSAnalyzer{},
+		&PythonAnalyzer{},
+		&RustAnalyzer{},
+		&JavaAnalyzer{},
+		&RubyAnalyzer{},
+		&PHPAnalyzer{},
+		&CFamilyAnalyzer{},
+	}
+}
+
+// buildAnalyzerRegistry indexes analyzers by the file extensions they
+// claim, so analyzeFile can dispatch with a map lookup.
+func buildAnalyzerRegistry(analyzers []LanguageAnalyzer) map[string]LanguageAnalyzer {
+	registry := make(map[string]LanguageAnalyzer)
+	for _, a := range analyzers {
+		for _, ext := range a.Extensions() {
+			registry[ext] = a
+		}
+	}
+	return registry
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 )
 
@@ -21,10 +22,25 @@ Flags:
   -compress         Enable gzip compression for output
   -max-size=<bytes> Maximum file size in bytes (default: 10MB)
   -unsafe           Allow processing of normally excluded paths
+  -ignore-file=<path> Extra gitignore-style file to apply
+  -no-gitignore     Don't honor .gitignore/.xdirignore files found in the tree
+  -tokenizer=<name> Tokenizer to use: cl100k_base, o200k_base, anthropic, words
+                    (default: cl100k_base, falling back to words offline)
+  -buffer-size=<bytes> Per-file read buffer size (default: 32KB)
+  -parallel=<N>     Number of files to read/encode concurrently (default: NumCPU)
+  -format=<fmt>     Output format: xml, json, jsonl, or md (default: xml)
+  -shard-max-tokens=<n> Split output into numbered shards under n tokens each
+  -allow-split      Allow an oversized file to be chunked across shards
+  -shard-overlap-lines=<n> Lines of repeated context between split chunks
+  -watch            Keep running, re-rendering output as watched files change
+  -serve=<addr>     With -watch, serve the current output over HTTP as SSE (e.g. :8080)
+  -git-ref=<rev>    Read sourcedir as a git repo at this revision instead of its working tree
 
 Notes:
   - If no source directory is specified, the current directory is used
-  - If no output file is specified, output.xml is used`)
+  - If no output file is specified, output.xml is used
+  - sourcedir may also be a .zip, .tar, or .tar.gz archive, or a
+    "git://path/to/repo#ref" URL, read without extracting or checking out`)
 }
 
 func main() {
@@ -37,6 +53,18 @@ func main() {
 	flag.Int64Var(&config.maxFileSize, "max-size", 10*1024*1024, "Maximum file size in bytes")
 	flag.BoolVar(&config.compress, "compress", false, "Compress output with gzip")
 	flag.BoolVar(&config.unsafeMode, "unsafe", false, "Allow processing of normally excluded paths")
+	flag.StringVar(&config.ignoreFile, "ignore-file", "", "Extra gitignore-style file to apply (e.g. .xdirignore)")
+	flag.BoolVar(&config.noGitignore, "no-gitignore", false, "Don't honor .gitignore/.xdirignore files found in the tree")
+	flag.StringVar(&config.tokenizer, "tokenizer", "", "Tokenizer to use: cl100k_base, o200k_base, anthropic, or words (default cl100k_base, falling back to words if its merge table can't be loaded)")
+	flag.IntVar(&config.bufferSize, "buffer-size", defaultBufferSize, "Per-file read buffer size in bytes")
+	flag.IntVar(&config.parallel, "parallel", runtime.NumCPU(), "Number of files to read/encode concurrently")
+	flag.StringVar(&config.format, "format", "xml", "Output format: xml, json, jsonl, or md")
+	flag.Int64Var(&config.shardMaxTokens, "shard-max-tokens", 0, "Split output into numbered shards under this many tokens each")
+	flag.BoolVar(&config.allowSplit, "allow-split", false, "Allow an oversized file to be chunked across shards")
+	flag.IntVar(&config.shardOverlapLines, "shard-overlap-lines", 0, "Lines of repeated context between split chunks")
+	flag.BoolVar(&config.watch, "watch", false, "Keep running, re-rendering the output whenever watched files change")
+	flag.StringVar(&config.serveAddr, "serve", "", "With -watch, also serve the current output over HTTP as SSE (e.g. :8080)")
+	flag.StringVar(&config.gitRef, "git-ref", "", "Read sourcedir as a git repo at this revision (e.g. HEAD~3) instead of its working tree")
 
 	var patternsStr string
 	var globPatternsStr string
@@ -63,6 +91,10 @@ func main() {
 	}
 	if len(args) > 1 {
 		config.outputFile = args[1]
+	} else if encoder, err := NewEncoder(config.format); err == nil {
+		// No explicit output file: match the default name's extension to
+		// the chosen format instead of always producing "output.xml".
+		config.outputFile = "output" + encoder.DefaultExtension()
 	}
 
 	// Parse patterns
@@ -81,32 +113,59 @@ func main() {
 		config.globPatterns = strings.Split(globPatternsStr, ",")
 	}
 
-	writer, cleanup, err := setupOutput(config)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error setting up output: %v\n", err)
-		os.Exit(1)
+	if config.watch {
+		if config.gitRef != "" {
+			fmt.Fprintf(os.Stderr, "-watch doesn't support -git-ref; it needs a real directory to receive filesystem events\n")
+			os.Exit(1)
+		}
+		if err := NewWatchRunner(config).Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error watching: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	stats, err := processFiles(config, writer)
-	if err != nil {
-		cleanup()
-		fmt.Fprintf(os.Stderr, "Error processing files: %v\n", err)
-		os.Exit(1)
-	}
+	var stats Stats
+	var newPath string
+
+	if config.shardMaxTokens > 0 {
+		// Sharded output manages its own files (0001-output.xml, ...)
+		// plus a manifest, so there's no single writer/rename step.
+		stats, err = processFilesSharded(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing files: %v\n", err)
+			os.Exit(1)
+		}
+		ext := filepath.Ext(config.outputFile)
+		newPath = strings.TrimSuffix(config.outputFile, ext) + ".manifest.json"
+	} else {
+		writer, cleanup, err := setupOutput(config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting up output: %v\n", err)
+			os.Exit(1)
+		}
 
-	// Close the file before renaming
-	cleanup()
+		stats, err = processFiles(config, writer)
+		if err != nil {
+			cleanup()
+			fmt.Fprintf(os.Stderr, "Error processing files: %v\n", err)
+			os.Exit(1)
+		}
 
-	// Rename the output file to include token count
-	dir := filepath.Dir(config.outputFile)
-	ext := filepath.Ext(config.outputFile)
-	base := strings.TrimSuffix(filepath.Base(config.outputFile), ext)
-	newName := fmt.Sprintf("%d-%s%s", stats.tokens, base, ext)
-	newPath := filepath.Join(dir, newName)
+		// Close the file before renaming
+		cleanup()
 
-	if err := os.Rename(config.outputFile, newPath); err != nil {
-		fmt.Fprintf(os.Stderr, "Error renaming output file: %v\n", err)
-		os.Exit(1)
+		// Rename the output file to include token count
+		dir := filepath.Dir(config.outputFile)
+		ext := filepath.Ext(config.outputFile)
+		base := strings.TrimSuffix(filepath.Base(config.outputFile), ext)
+		newName := fmt.Sprintf("%d-%s%s", stats.tokens, base, ext)
+		newPath = filepath.Join(dir, newName)
+
+		if err := os.Rename(config.outputFile, newPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error renaming output file: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	fmt.Printf("\nProcessing complete:\n")
@@ -114,5 +173,13 @@ func main() {
 	fmt.Printf("- Total size: %.2f MB\n", float64(stats.bytesProc)/(1024*1024))
 	fmt.Printf("- Total tokens: %d\n", stats.tokens)
 	fmt.Printf("- Errors: %d\n", stats.errors)
+
+	if len(stats.topFiles) > 0 {
+		fmt.Printf("\nLargest files by token count:\n")
+		for _, f := range stats.topFiles {
+			fmt.Printf("  %8d  %s\n", f.Tokens, f.Name)
+		}
+	}
+
 	fmt.Printf("\nOutput written to: %s\n", newPath)
 }
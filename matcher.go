@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"path/filepath"
+	"path"
 	"strings"
 )
 
@@ -16,10 +16,11 @@ func cleanPattern(pattern string) string {
 	return pattern
 }
 
-// isFileMatch checks if the file matches any of the patterns
-func isFileMatch(path string, config Config) bool {
-	ext := cleanPattern(filepath.Ext(path))
-	fmt.Printf("Checking file %s with extension %s\n", path, ext)
+// isFileMatch checks if relPath (an fs.FS-style path) matches any of the
+// patterns
+func isFileMatch(relPath string, config Config) bool {
+	ext := cleanPattern(path.Ext(relPath))
+	fmt.Printf("Checking file %s with extension %s\n", relPath, ext)
 
 	// Check file extensions first
 	if len(config.filePatterns) > 0 {
@@ -39,7 +40,7 @@ func isFileMatch(path string, config Config) bool {
 	if len(config.globPatterns) > 0 {
 		fmt.Printf("Checking against glob patterns: %v\n", config.globPatterns)
 		for _, pattern := range config.globPatterns {
-			matched, err := filepath.Match(pattern, filepath.Base(path))
+			matched, err := path.Match(pattern, path.Base(relPath))
 			if err == nil && matched {
 				fmt.Printf("File matched glob pattern %s\n", pattern)
 				return true
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestIsFileMatchGlobPatternUsesFSStylePath(t *testing.T) {
+	config := Config{globPatterns: []string{"*.go"}}
+
+	// relPath is always fs.FS-style (slash-separated, no drive letter),
+	// including for archive/git sources on a Windows host - path.Base must
+	// be used rather than filepath.Base so this doesn't depend on the
+	// host OS's separator.
+	if !isFileMatch("pkg/sub/main.go", config) {
+		t.Errorf(`isFileMatch("pkg/sub/main.go") = false, want true`)
+	}
+	if isFileMatch("pkg/sub/main.py", config) {
+		t.Errorf(`isFileMatch("pkg/sub/main.py") = true, want false`)
+	}
+}
+
+func TestIsFileMatchExtensionPattern(t *testing.T) {
+	config := Config{filePatterns: []string{"md"}}
+	if !isFileMatch("docs/readme.md", config) {
+		t.Errorf(`isFileMatch("docs/readme.md") = false, want true`)
+	}
+	if isFileMatch("docs/readme.go", config) {
+		t.Errorf(`isFileMatch("docs/readme.go") = true, want false`)
+	}
+}
+
+func TestIsFileMatchDefaultPatterns(t *testing.T) {
+	config := Config{}
+	if !isFileMatch("main.go", config) {
+		t.Errorf(`isFileMatch("main.go") = false, want true (default pattern)`)
+	}
+	if isFileMatch("image.png", config) {
+		t.Errorf(`isFileMatch("image.png") = true, want false`)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// setupOutput opens config.outputFile and returns the writer processFiles
+// should stream XML into, plus a cleanup func that flushes and closes
+// everything. The caller must invoke cleanup before relying on the file's
+// final contents (e.g. before renaming it to embed the token count).
+func setupOutput(config Config) (io.Writer, func(), error) {
+	f, err := os.Create(config.outputFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating output file %s: %v", config.outputFile, err)
+	}
+
+	if !config.compress {
+		return f, func() { f.Close() }, nil
+	}
+
+	gz := gzip.NewWriter(f)
+	cleanup := func() {
+		gz.Close()
+		f.Close()
+	}
+	return gz, cleanup, nil
+}
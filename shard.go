@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ShardWriter packs encoded files greedily into numbered output files
+// (0001-output.xml, 0002-output.xml, ...) that each stay under
+// maxTokens, implementing fragmentSink so it's a drop-in destination for
+// StreamProcessor's pipeline. A file never splits across shards unless
+// allowSplit is set, in which case an oversized file is chunked along
+// line boundaries with overlapLines of repeated context between chunks.
+type ShardWriter struct {
+	baseDir      string
+	baseName     string
+	ext          string
+	maxTokens    int64
+	allowSplit   bool
+	overlapLines int
+
+	encoder      Encoder
+	shardIndex   int
+	shardTokens  int64
+	shardFiles   int
+	curFile      *os.File
+	wroteInShard bool
+	manifest     shardManifest
+}
+
+// shardManifest is written alongside the shards once processing
+// finishes, replacing the single-file "<tokens>-output.xml" naming
+// convention with a record of every shard's own token total.
+type shardManifest struct {
+	Shards []shardManifestEntry `json:"shards"`
+}
+
+type shardManifestEntry struct {
+	File   string `json:"file"`
+	Tokens int64  `json:"tokens"`
+	Files  int    `json:"files"`
+}
+
+// NewShardWriter builds a ShardWriter that names shards after
+// outputPath's base name and extension.
+func NewShardWriter(outputPath string, maxTokens int64, allowSplit bool, overlapLines int) *ShardWriter {
+	ext := filepath.Ext(outputPath)
+	return &ShardWriter{
+		baseDir:      filepath.Dir(outputPath),
+		baseName:     strings.TrimSuffix(filepath.Base(outputPath), ext),
+		ext:          ext,
+		maxTokens:    maxTokens,
+		allowSplit:   allowSplit,
+		overlapLines: overlapLines,
+	}
+}
+
+// WriteHeader implements fragmentSink by opening the first shard.
+func (sw *ShardWriter) WriteHeader(encoder Encoder) error {
+	sw.encoder = encoder
+	return sw.openShard()
+}
+
+// WriteFooter implements fragmentSink by closing whichever shard is
+// still open. The manifest itself is written by Close.
+func (sw *ShardWriter) WriteFooter(encoder Encoder) error {
+	return sw.closeShard()
+}
+
+// Close finalizes the manifest file once every shard has been written.
+// Callers must call this after WriteFooter (RunSharded does so).
+func (sw *ShardWriter) Close() error {
+	data, err := json.MarshalIndent(sw.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(sw.baseDir, sw.baseName+".manifest.json")
+	return os.WriteFile(manifestPath, data, 0o644)
+}
+
+func (sw *ShardWriter) openShard() error {
+	sw.shardIndex++
+	name := fmt.Sprintf("%04d-%s%s", sw.shardIndex, sw.baseName, sw.ext)
+	f, err := os.Create(filepath.Join(sw.baseDir, name))
+	if err != nil {
+		return err
+	}
+
+	sw.curFile = f
+	sw.shardTokens = 0
+	sw.shardFiles = 0
+	sw.wroteInShard = false
+
+	_, err = f.Write(sw.encoder.Header())
+	return err
+}
+
+func (sw *ShardWriter) closeShard() error {
+	if sw.curFile == nil {
+		return nil
+	}
+
+	if _, err := sw.curFile.Write(sw.encoder.Footer()); err != nil {
+		return err
+	}
+	name := filepath.Base(sw.curFile.Name())
+	if err := sw.curFile.Close(); err != nil {
+		return err
+	}
+
+	sw.manifest.Shards = append(sw.manifest.Shards, shardManifestEntry{
+		File:   name,
+		Tokens: sw.shardTokens,
+		Files:  sw.shardFiles,
+	})
+	sw.curFile = nil
+	return nil
+}
+
+// WriteFile implements fragmentSink. It rolls over to a new shard before
+// a file that would push the current one over budget, and splits files
+// that alone exceed the budget (when allowSplit is set).
+func (sw *ShardWriter) WriteFile(relPath string, data []byte, tokens int64, content *FileContent) error {
+	if sw.maxTokens > 0 && tokens > sw.maxTokens {
+		if !sw.allowSplit {
+			fmt.Printf("Warning: %s (%d tokens) exceeds -shard-max-tokens=%d; writing it whole (pass -allow-split to chunk it)\n", relPath, tokens, sw.maxTokens)
+		} else {
+			return sw.writeSplit(relPath, tokens, content)
+		}
+	}
+
+	return sw.place(relPath, data, tokens)
+}
+
+// place writes one already-encoded fragment to the current shard,
+// rolling over to a fresh shard first if it wouldn't fit.
+func (sw *ShardWriter) place(relPath string, data []byte, tokens int64) error {
+	if sw.maxTokens > 0 && sw.wroteInShard && sw.shardTokens+tokens > sw.maxTokens {
+		if err := sw.closeShard(); err != nil {
+			return err
+		}
+		if err := sw.openShard(); err != nil {
+			return err
+		}
+	}
+
+	if sw.wroteInShard {
+		if _, err := sw.curFile.Write(sw.encoder.Separator()); err != nil {
+			return err
+		}
+	}
+	sw.wroteInShard = true
+	sw.shardTokens += tokens
+	sw.shardFiles++
+
+	_, err := sw.curFile.Write(data)
+	return err
+}
+
+// writeSplit breaks an oversized file into line-boundary chunks (with
+// overlapLines of repeated context between consecutive chunks), each
+// re-encoded and placed as its own pseudo-file so no single shard
+// receives more than roughly maxTokens worth of content.
+func (sw *ShardWriter) writeSplit(relPath string, tokens int64, content *FileContent) error {
+	lines := strings.Split(content.Content, "\n")
+
+	numChunks := int((tokens + sw.maxTokens - 1) / sw.maxTokens)
+	if numChunks < 1 {
+		numChunks = 1
+	}
+	linesPerChunk := (len(lines) + numChunks - 1) / numChunks
+	if linesPerChunk < 1 {
+		linesPerChunk = 1
+	}
+
+	for i := 0; i < numChunks; i++ {
+		start := i * linesPerChunk
+		if start >= len(lines) {
+			break
+		}
+		end := start + linesPerChunk
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if i > 0 {
+			start -= sw.overlapLines
+			if start < 0 {
+				start = 0
+			}
+		}
+
+		chunkText := strings.Join(lines[start:end], "\n")
+		chunk := &FileContent{
+			Name:         fmt.Sprintf("%s (part %d/%d)", relPath, i+1, numChunks),
+			Size:         int64(len(chunkText)),
+			Content:      chunkText,
+			Dependencies: content.Dependencies,
+		}
+
+		data, err := sw.encoder.EncodeFile(chunk)
+		if err != nil {
+			return err
+		}
+
+		// Token counts aren't re-run through the tokenizer per chunk;
+		// splitting evenly by chunk count is a close enough estimate for
+		// shard packing purposes.
+		chunkTokens := tokens / int64(numChunks)
+		if err := sw.place(chunk.Name, data, chunkTokens); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
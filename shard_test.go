@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readManifest loads the shard manifest ShardWriter wrote alongside its
+// shards.
+func readManifest(t *testing.T, outputPath string) shardManifest {
+	t.Helper()
+	ext := filepath.Ext(outputPath)
+	base := outputPath[:len(outputPath)-len(ext)]
+	data, err := os.ReadFile(base + ".manifest.json")
+	if err != nil {
+		t.Fatalf("reading manifest: %v", err)
+	}
+	var m shardManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("unmarshaling manifest: %v", err)
+	}
+	return m
+}
+
+func TestShardWriterRollsOverAtTokenBudget(t *testing.T) {
+	dir := t.TempDir()
+	sw := NewShardWriter(filepath.Join(dir, "output.jsonl"), 10, false, 0)
+	enc := &JSONLEncoder{}
+
+	if err := sw.WriteHeader(enc); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	// Three 6-token files: budget 10 means the 2nd must roll into a new
+	// shard (6+6>10), and the 3rd starts a third shard the same way.
+	for i := 0; i < 3; i++ {
+		content := &FileContent{Name: "f.go"}
+		data, err := enc.EncodeFile(content)
+		if err != nil {
+			t.Fatalf("EncodeFile: %v", err)
+		}
+		if err := sw.WriteFile("f.go", data, 6, content); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := sw.WriteFooter(enc); err != nil {
+		t.Fatalf("WriteFooter: %v", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	m := readManifest(t, filepath.Join(dir, "output.jsonl"))
+	if len(m.Shards) != 3 {
+		t.Fatalf("got %d shards, want 3: %+v", len(m.Shards), m.Shards)
+	}
+	for _, s := range m.Shards {
+		if s.Files != 1 || s.Tokens != 6 {
+			t.Fatalf("shard %+v: want 1 file of 6 tokens", s)
+		}
+	}
+}
+
+func TestShardWriterPacksMultipleFilesPerShard(t *testing.T) {
+	dir := t.TempDir()
+	sw := NewShardWriter(filepath.Join(dir, "output.jsonl"), 10, false, 0)
+	enc := &JSONLEncoder{}
+
+	if err := sw.WriteHeader(enc); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		content := &FileContent{Name: "f.go"}
+		data, _ := enc.EncodeFile(content)
+		if err := sw.WriteFile("f.go", data, 4, content); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := sw.WriteFooter(enc); err != nil {
+		t.Fatalf("WriteFooter: %v", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	m := readManifest(t, filepath.Join(dir, "output.jsonl"))
+	if len(m.Shards) != 1 || m.Shards[0].Files != 2 || m.Shards[0].Tokens != 8 {
+		t.Fatalf("got %+v, want a single shard with 2 files totalling 8 tokens", m.Shards)
+	}
+}
+
+func TestShardWriterSplitsOversizedFile(t *testing.T) {
+	dir := t.TempDir()
+	sw := NewShardWriter(filepath.Join(dir, "output.jsonl"), 10, true, 0)
+	enc := &JSONLEncoder{}
+
+	if err := sw.WriteHeader(enc); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	content := &FileContent{Name: "big.go", Content: "l1\nl2\nl3\nl4\nl5\nl6"}
+	data, _ := enc.EncodeFile(content)
+	// 30 tokens over a budget of 10 forces a 3-way split.
+	if err := sw.WriteFile("big.go", data, 30, content); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := sw.WriteFooter(enc); err != nil {
+		t.Fatalf("WriteFooter: %v", err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	m := readManifest(t, filepath.Join(dir, "output.jsonl"))
+	totalFiles := 0
+	for _, s := range m.Shards {
+		totalFiles += s.Files
+	}
+	if totalFiles != 3 {
+		t.Fatalf("got %d total chunk-files across %d shards, want 3", totalFiles, len(m.Shards))
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing/fstest"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Source is anything xdir can read a file tree from: a local directory,
+// an archive, or a snapshot of a git ref. It's a thin wrapper around
+// fs.FS so the rest of the pipeline (Ignorer, the walk/encode pass in
+// StreamProcessor) only ever has to deal in fs.FS terms, regardless of
+// where the bytes actually came from.
+type Source interface {
+	// FS returns the file tree to walk, rooted at ".".
+	FS() fs.FS
+	// Label is a human-readable description of the source, used in
+	// progress output in place of a bare directory path.
+	Label() string
+}
+
+// OpenSource resolves target into a Source. target is treated as, in
+// order:
+//  1. a git ref source, if gitRef is non-empty: target is a path to a git
+//     repository (bare or with a working tree) and gitRef names the
+//     revision to read (branch, tag, or commit-ish like "HEAD~3").
+//  2. an archive, if target's extension is .zip, .tar, .tar.gz, or .tgz.
+//  3. a "git://path/to/repo#ref" URL, equivalent to (1) with gitRef taken
+//     from the fragment.
+//  4. otherwise, a local directory.
+func OpenSource(target string, gitRef string) (Source, error) {
+	if gitRef != "" {
+		return newGitSource(target, gitRef)
+	}
+
+	if rest, ok := strings.CutPrefix(target, "git://"); ok {
+		repoPath, ref, hasRef := strings.Cut(rest, "#")
+		if !hasRef {
+			ref = "HEAD"
+		}
+		return newGitSource(repoPath, ref)
+	}
+
+	switch {
+	case strings.HasSuffix(target, ".zip"):
+		return newZipSource(target)
+	case strings.HasSuffix(target, ".tar.gz"), strings.HasSuffix(target, ".tgz"):
+		return newTarSource(target, true)
+	case strings.HasSuffix(target, ".tar"):
+		return newTarSource(target, false)
+	default:
+		return newLocalSource(target)
+	}
+}
+
+// localSource is the original xdir behavior: read straight off the host
+// filesystem via os.DirFS.
+type localSource struct {
+	dir string
+	fs  fs.FS
+}
+
+func newLocalSource(dir string) (Source, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving directory %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &localSource{dir: dir, fs: os.DirFS(dir)}, nil
+}
+
+func (s *localSource) FS() fs.FS     { return s.fs }
+func (s *localSource) Label() string { return s.dir }
+
+// archiveSource wraps an in-memory fstest.MapFS built once from an
+// archive's entries. fstest.MapFS is a plain map-backed fs.FS
+// implementation from the standard library; reusing it here avoids
+// hand-rolling one just to represent "a fixed set of named files" for
+// zip/tar sources and git trees alike.
+type archiveSource struct {
+	path  string
+	mapFS fstest.MapFS
+}
+
+func (s *archiveSource) FS() fs.FS     { return s.mapFS }
+func (s *archiveSource) Label() string { return s.path }
+
+// newZipSource reads every entry of a .zip file into memory up front.
+// xdir's size limits (-max-size) are applied later in the normal walk, so
+// this doesn't re-check them here.
+func newZipSource(zipPath string) (Source, error) {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening zip %s: %v", zipPath, err)
+	}
+	defer r.Close()
+
+	mapFS := fstest.MapFS{}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from %s: %v", f.Name, zipPath, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from %s: %v", f.Name, zipPath, err)
+		}
+		mapFS[cleanArchiveName(f.Name)] = &fstest.MapFile{Data: data, Mode: f.Mode()}
+	}
+
+	return &archiveSource{path: zipPath, mapFS: mapFS}, nil
+}
+
+// newTarSource reads every entry of a .tar (optionally gzip-compressed)
+// file into memory up front, the same way newZipSource does.
+func newTarSource(tarPath string, gzipped bool) (Source, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %v", tarPath, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gzipped {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing %s: %v", tarPath, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	mapFS := fstest.MapFS{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", tarPath, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s from %s: %v", hdr.Name, tarPath, err)
+		}
+		mapFS[cleanArchiveName(hdr.Name)] = &fstest.MapFile{Data: data, Mode: os.FileMode(hdr.Mode)}
+	}
+
+	return &archiveSource{path: tarPath, mapFS: mapFS}, nil
+}
+
+// newGitSource reads the tree of a single commit straight out of repoPath's
+// object database via go-git, with no working tree checkout: ref is
+// resolved to a commit, the commit's tree is walked, and every blob is
+// read into an in-memory fs.FS.
+func newGitSource(repoPath string, ref string) (Source, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening git repo %s: %v", repoPath, err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving %s in %s: %v", ref, repoPath, err)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("error reading commit %s: %v", hash, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("error reading tree for %s: %v", hash, err)
+	}
+
+	mapFS := fstest.MapFS{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error walking tree for %s: %v", hash, err)
+		}
+		if !entry.Mode.IsFile() {
+			continue
+		}
+
+		blob, err := object.GetBlob(repo.Storer, entry.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("error reading blob %s: %v", name, err)
+		}
+		blobReader, err := blob.Reader()
+		if err != nil {
+			return nil, fmt.Errorf("error reading blob %s: %v", name, err)
+		}
+		data, err := io.ReadAll(blobReader)
+		blobReader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading blob %s: %v", name, err)
+		}
+
+		mapFS[cleanArchiveName(name)] = &fstest.MapFile{Data: data}
+	}
+
+	return &archiveSource{path: fmt.Sprintf("%s#%s", repoPath, ref), mapFS: mapFS}, nil
+}
+
+// cleanArchiveName normalizes an archive/tree entry name into the
+// slash-separated, no-leading-slash form fs.FS (and fstest.MapFS in
+// particular) requires, stripping a common single top-level directory
+// prefix some archives and .tar.gz releases wrap everything in is left to
+// the caller's ignore/pattern filtering rather than guessed here.
+func cleanArchiveName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.TrimPrefix(path.Clean(name), "/")
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestCleanArchiveName(t *testing.T) {
+	cases := map[string]string{
+		"foo/bar.go":   "foo/bar.go",
+		"/foo/bar.go":  "foo/bar.go",
+		"foo\\bar.go":  "foo/bar.go",
+		"./foo/bar.go": "foo/bar.go",
+		"foo//bar.go":  "foo/bar.go",
+	}
+	for in, want := range cases {
+		if got := cleanArchiveName(in); got != want {
+			t.Errorf("cleanArchiveName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create zip: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+}
+
+func TestNewZipSourceReadsEntries(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "archive.zip")
+	writeZip(t, zipPath, map[string]string{
+		"main.go":       "package main",
+		"pkg/helper.go": "package pkg",
+	})
+
+	src, err := newZipSource(zipPath)
+	if err != nil {
+		t.Fatalf("newZipSource: %v", err)
+	}
+	if src.Label() != zipPath {
+		t.Errorf("Label() = %q, want %q", src.Label(), zipPath)
+	}
+
+	data, err := fs.ReadFile(src.FS(), "main.go")
+	if err != nil {
+		t.Fatalf("ReadFile main.go: %v", err)
+	}
+	if string(data) != "package main" {
+		t.Errorf("main.go content = %q", data)
+	}
+	if _, err := fs.Stat(src.FS(), "pkg/helper.go"); err != nil {
+		t.Errorf("expected pkg/helper.go present: %v", err)
+	}
+}
+
+func writeTar(t *testing.T, path string, gzipped bool, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create tar: %v", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("tar header %s: %v", name, err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("tar write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+
+	if gzipped {
+		gw := gzip.NewWriter(f)
+		if _, err := gw.Write(buf.Bytes()); err != nil {
+			t.Fatalf("gzip write: %v", err)
+		}
+		if err := gw.Close(); err != nil {
+			t.Fatalf("gzip close: %v", err)
+		}
+		return
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		t.Fatalf("tar write file: %v", err)
+	}
+}
+
+func TestNewTarSourceReadsEntries(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "archive.tar")
+	writeTar(t, tarPath, false, map[string]string{"a.txt": "hello"})
+
+	src, err := newTarSource(tarPath, false)
+	if err != nil {
+		t.Fatalf("newTarSource: %v", err)
+	}
+	data, err := fs.ReadFile(src.FS(), "a.txt")
+	if err != nil {
+		t.Fatalf("ReadFile a.txt: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("a.txt content = %q, want hello", data)
+	}
+}
+
+func TestNewTarSourceGzippedReadsEntries(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "archive.tar.gz")
+	writeTar(t, tarPath, true, map[string]string{"a.txt": "hello gz"})
+
+	src, err := newTarSource(tarPath, true)
+	if err != nil {
+		t.Fatalf("newTarSource: %v", err)
+	}
+	data, err := fs.ReadFile(src.FS(), "a.txt")
+	if err != nil {
+		t.Fatalf("ReadFile a.txt: %v", err)
+	}
+	if string(data) != "hello gz" {
+		t.Errorf("a.txt content = %q, want %q", data, "hello gz")
+	}
+}
+
+func TestOpenSourceDispatchesByExtension(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "archive.zip")
+	writeZip(t, zipPath, map[string]string{"f.go": "x"})
+
+	src, err := OpenSource(zipPath, "")
+	if err != nil {
+		t.Fatalf("OpenSource zip: %v", err)
+	}
+	if _, ok := src.(*archiveSource); !ok {
+		t.Errorf("OpenSource(%q) = %T, want *archiveSource", zipPath, src)
+	}
+
+	localSrc, err := OpenSource(dir, "")
+	if err != nil {
+		t.Fatalf("OpenSource dir: %v", err)
+	}
+	if _, ok := localSrc.(*localSource); !ok {
+		t.Errorf("OpenSource(%q) = %T, want *localSource", dir, localSrc)
+	}
+}
+
+func TestNewGitSourceReadsCommitTree(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("PlainInit: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if _, err := wt.Add("main.go"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	commitHash, err := wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	src, err := newGitSource(dir, commitHash.String())
+	if err != nil {
+		t.Fatalf("newGitSource: %v", err)
+	}
+	data, err := fs.ReadFile(src.FS(), "main.go")
+	if err != nil {
+		t.Fatalf("ReadFile main.go: %v", err)
+	}
+	if string(data) != "package main" {
+		t.Errorf("main.go content = %q", data)
+	}
+}
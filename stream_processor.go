@@ -2,12 +2,14 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 const (
@@ -17,8 +19,12 @@ const (
 
 // StreamProcessor handles streaming file processing
 type StreamProcessor struct {
-	bufferSize int
-	writer     io.Writer
+	bufferSize  int
+	parallel    int
+	writer      io.Writer
+	ignorer     *Ignorer
+	ignoreFile  string
+	noGitignore bool
 }
 
 // NewStreamProcessor creates a new processor with specified buffer size
@@ -29,57 +35,75 @@ func NewStreamProcessor(bufferSize int) *StreamProcessor {
 	if bufferSize > maxBufferSize {
 		bufferSize = maxBufferSize
 	}
-	return &StreamProcessor{bufferSize: bufferSize}
+	return &StreamProcessor{bufferSize: bufferSize, parallel: 1}
 }
 
-// ProcessDirectory processes an entire directory streaming to XML
-func (p *StreamProcessor) ProcessDirectory(dir string, w io.Writer) error {
+// ProcessDirectory processes an entire directory streaming to XML. It
+// predates Source/Run's configurable pipeline and is kept as a minimal,
+// dependency-free entry point.
+func (p *StreamProcessor) ProcessDirectory(fsys fs.FS, w io.Writer) error {
 	p.writer = w
 
+	ignorer, err := NewIgnorer(fsys, p.ignoreFile, p.noGitignore)
+	if err != nil {
+		return err
+	}
+	p.ignorer = ignorer
+
 	// Write XML header
 	if _, err := fmt.Fprintf(w, "%s<files>\n", xml.Header); err != nil {
 		return err
 	}
 
 	// Process all files
-	if err := filepath.Walk(dir, p.processPath); err != nil {
+	if err := fs.WalkDir(fsys, ".", func(relPath string, d fs.DirEntry, err error) error {
+		return p.walkPath(fsys, relPath, d, err)
+	}); err != nil {
 		return err
 	}
 
 	// Write XML footer
-	_, err := fmt.Fprintf(w, "</files>\n")
+	_, err = fmt.Fprintf(w, "</files>\n")
 	return err
 }
 
-// processPath handles each file/directory during the walk
-func (p *StreamProcessor) processPath(path string, info os.FileInfo, err error) error {
+// walkPath handles each file/directory during ProcessDirectory's walk
+func (p *StreamProcessor) walkPath(fsys fs.FS, relPath string, d fs.DirEntry, err error) error {
 	if err != nil {
 		return err
 	}
+	if relPath == "." {
+		return nil
+	}
 
-	if info.IsDir() {
-		return nil // Skip directories
+	if d.IsDir() {
+		if err := p.ignorer.Enter(relPath); err != nil {
+			return err
+		}
+		if p.ignorer.Match(relPath, true) {
+			return fs.SkipDir
+		}
+		return nil // Skip directories themselves
 	}
 
-	if isExcluded(path) {
+	if p.ignorer.Match(relPath, false) {
 		return nil // Skip excluded files
 	}
 
-	return p.processFile(path, info)
+	return p.processFile(fsys, relPath)
 }
 
 // processFile handles a single file
-func (p *StreamProcessor) processFile(path string, info os.FileInfo) error {
-	file, err := os.Open(path)
+func (p *StreamProcessor) processFile(fsys fs.FS, relPath string) error {
+	file, err := fsys.Open(relPath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// Start file element
-	relPath, err := filepath.Rel(".", path)
+	info, err := file.Stat()
 	if err != nil {
-		relPath = path
+		return err
 	}
 
 	fmt.Fprintf(p.writer, `  <file path="%s" size="%d">`+"\n", relPath, info.Size())
@@ -99,15 +123,17 @@ func (p *StreamProcessor) processFile(path string, info os.FileInfo) error {
 		fmt.Fprintf(p.writer, "    </imports>\n")
 	}
 
-	// Reset file pointer for content
-	if _, err := file.Seek(0, 0); err != nil {
+	// Re-open the file for content, since fs.File isn't guaranteed seekable
+	content, err := fsys.Open(relPath)
+	if err != nil {
 		return err
 	}
+	defer content.Close()
 
 	// Stream file content
 	fmt.Fprintf(p.writer, "    <content><![CDATA[\n")
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(content)
 	scanner.Buffer(make([]byte, p.bufferSize), p.bufferSize)
 
 	for scanner.Scan() {
@@ -148,20 +174,341 @@ func (p *StreamProcessor) analyzeContext(r io.Reader) (Context, error) {
 	return ctx, scanner.Err()
 }
 
-// isExcluded checks if a path should be excluded
-func isExcluded(path string) bool {
-	excludedPaths := []string{
-		"node_modules",
-		".git",
-		".env",
-		".DS_Store",
+// fragmentSink receives the pipeline's output: a header, one encoded
+// fragment per file in walk order, and a footer. Splitting this out from
+// Run lets the same walk/analyze/encode pipeline feed either a single
+// output file (singleFileSink) or a token-budgeted set of shards
+// (ShardWriter, for -shard-max-tokens).
+type fragmentSink interface {
+	WriteHeader(encoder Encoder) error
+	// WriteFile hands off one encoded file. content is the original,
+	// fully-populated FileContent (name/content/dependencies) the
+	// fragment was rendered from, so a sink that needs to re-render
+	// part of it (ShardWriter splitting an oversized file) can do so
+	// without re-reading the file from disk.
+	WriteFile(relPath string, data []byte, tokens int64, content *FileContent) error
+	WriteFooter(encoder Encoder) error
+}
+
+// singleFileSink writes every fragment to a single io.Writer, inserting
+// the encoder's separator between files. This is xdir's original
+// single-file behavior.
+type singleFileSink struct {
+	w       io.Writer
+	encoder Encoder
+	wrote   bool
+}
+
+func (s *singleFileSink) WriteHeader(encoder Encoder) error {
+	s.encoder = encoder
+	_, err := s.w.Write(encoder.Header())
+	return err
+}
+
+func (s *singleFileSink) WriteFile(relPath string, data []byte, tokens int64, content *FileContent) error {
+	if s.wrote {
+		if _, err := s.w.Write(s.encoder.Separator()); err != nil {
+			return err
+		}
+	}
+	s.wrote = true
+	_, err := s.w.Write(data)
+	return err
+}
+
+func (s *singleFileSink) WriteFooter(encoder Encoder) error {
+	_, err := s.w.Write(encoder.Footer())
+	return err
+}
+
+// Run is the full pipeline processFiles delegates to: walk -> resolve
+// dependencies -> stream each file through a bounded worker pool -> write
+// fragments out in walk order, tallying tokens as they pass through.
+// Unlike ProcessDirectory above, Run honors the full Config (patterns,
+// size limits, dependency analysis, tokenizer/format selection) with a
+// working set bounded to roughly config.parallel in-flight file buffers
+// rather than the whole tree, and reads from source rather than assuming
+// a local directory - source may be a local dir, an archive, or a git
+// ref (see source.go).
+func (p *StreamProcessor) Run(config Config, source Source, writer io.Writer) (Stats, error) {
+	return p.run(config, source, &singleFileSink{w: writer})
+}
+
+// RunSharded is Run's counterpart for -shard-max-tokens: instead of one
+// output file, it packs files greedily into numbered shards that each
+// stay under the token budget, and writes a manifest listing every
+// shard's token total once done.
+func (p *StreamProcessor) RunSharded(config Config, source Source, sw *ShardWriter) (Stats, error) {
+	stats, err := p.run(config, source, sw)
+	if err != nil {
+		return stats, err
+	}
+	if err := sw.Close(); err != nil {
+		return stats, fmt.Errorf("error finalizing shards: %v", err)
+	}
+	return stats, nil
+}
+
+// run drives the shared pipeline against an arbitrary fragmentSink, so
+// Run (single file) and RunSharded (token-budgeted shards) share every
+// step except where the encoded bytes ultimately land.
+func (p *StreamProcessor) run(config Config, source Source, sink fragmentSink) (Stats, error) {
+	var stats Stats
+	fsys := source.FS()
+
+	fmt.Printf("Processing: %s\n", source.Label())
+
+	ignorer, err := NewIgnorer(fsys, config.ignoreFile, config.noGitignore)
+	if err != nil {
+		return stats, fmt.Errorf("error setting up ignore rules: %v", err)
+	}
+	p.ignorer = ignorer
+
+	tokenizer, err := NewTokenizer(config.tokenizer)
+	if err != nil {
+		return stats, fmt.Errorf("error setting up tokenizer: %v", err)
+	}
+
+	encoder, err := NewEncoder(config.format)
+	if err != nil {
+		return stats, fmt.Errorf("error setting up output format: %v", err)
+	}
+
+	analyzer := NewDependencyAnalyzer(fsys)
+
+	// Pass 1: walk the tree, registering each matched file's path and
+	// size with the analyzer. This is enough for AnalyzeDependencies to
+	// parse imports/exports straight out of fsys without us holding any
+	// file content in memory yet.
+	entries, err := p.collectEntries(fsys, config, analyzer, &stats)
+	if err != nil {
+		return stats, err
+	}
+
+	fmt.Println("Analyzing dependencies...")
+	if err := analyzer.AnalyzeDependencies(); err != nil {
+		fmt.Printf("Error analyzing dependencies: %v\n", err)
+		stats.errors++
+	}
+
+	if err := sink.WriteHeader(encoder); err != nil {
+		return stats, fmt.Errorf("error writing output header: %v", err)
+	}
+
+	if err := p.streamEncodeFiles(fsys, entries, analyzer, tokenizer, encoder, sink, &stats); err != nil {
+		return stats, err
+	}
+	flushTokenizer(tokenizer)
+
+	if err := sink.WriteFooter(encoder); err != nil {
+		return stats, fmt.Errorf("error writing output footer: %v", err)
+	}
+
+	fmt.Printf("Token count: %d\n", stats.tokens)
+	return stats, nil
+}
+
+// walkEntry is a file that survived filtering during the directory walk,
+// queued up for the streaming encode pass. path is fs.FS-relative
+// (slash-separated, no leading slash).
+type walkEntry struct {
+	relPath string
+	size    int64
+}
+
+// collectEntries walks fsys applying ignore/pattern/size filters,
+// registering each surviving file with analyzer, and returns them in walk
+// order for the streaming encode pass.
+func (p *StreamProcessor) collectEntries(fsys fs.FS, config Config, analyzer *DependencyAnalyzer, stats *Stats) ([]*walkEntry, error) {
+	var entries []*walkEntry
+
+	outRelPath, excludeOut := outputFileRelPath(config)
+
+	err := fs.WalkDir(fsys, ".", func(relPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Printf("Error accessing path %s: %v\n", relPath, err)
+			stats.errors++
+			return nil
+		}
+
+		if relPath == "." {
+			return nil
+		}
+
+		if d.IsDir() {
+			if err := p.ignorer.Enter(relPath); err != nil {
+				fmt.Printf("Error loading ignore rules for %s: %v\n", relPath, err)
+				stats.errors++
+			}
+			if isExcludedPath(relPath, true, config, p.ignorer) {
+				fmt.Printf("Skipping excluded directory: %s\n", relPath)
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if excludeOut && relPath == outRelPath {
+			fmt.Printf("Skipping own output file: %s\n", relPath)
+			return nil
+		}
+
+		if isExcludedPath(relPath, false, config, p.ignorer) {
+			return nil
+		}
+
+		if !isFileMatch(relPath, config) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			fmt.Printf("Error reading info for %s: %v\n", relPath, err)
+			stats.errors++
+			return nil
+		}
+
+		if config.maxFileSize > 0 && info.Size() > config.maxFileSize {
+			fmt.Printf("Skipping file exceeding size limit: %s (%d bytes)\n", relPath, info.Size())
+			stats.errors++
+			return nil
+		}
+
+		analyzer.RegisterFile(relPath, &FileContent{Name: relPath, Size: info.Size()})
+		entries = append(entries, &walkEntry{relPath: relPath, size: info.Size()})
+
+		stats.filesProc++
+		stats.bytesProc += info.Size()
+		return nil
+	})
+
+	return entries, err
+}
+
+// outputFileRelPath resolves config.outputFile to its fs.FS-style path
+// (slash-separated, relative to config.targetDir), so collectEntries can
+// exclude it from the tree it walks. Without this, a render written into
+// (or under) the watched/source directory - the default CLI invocation,
+// "xdir -watch ." writing "output.xml" into "." - gets re-ingested as a
+// source file on the very next render, nesting each render inside the
+// next forever. ok is false if outputFile doesn't resolve to somewhere
+// under targetDir (an absolute path elsewhere, or a source - an archive,
+// a git ref - that outputFile can't live inside anyway).
+func outputFileRelPath(config Config) (relPath string, ok bool) {
+	absOut, err := filepath.Abs(config.outputFile)
+	if err != nil {
+		return "", false
+	}
+	absDir, err := filepath.Abs(config.targetDir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absDir, absOut)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// encodedFile is one worker's output: the fully-rendered <file> fragment
+// for a single walkEntry, ready to be written out in order.
+type encodedFile struct {
+	relPath string
+	data    []byte
+	content *FileContent
+	tokens  int64
+	err     error
+}
+
+// streamEncodeFiles reads and encodes each entry out of fsys using a
+// bounded pool of workers, then hands the results to sink strictly in
+// walk order via an ordered merge stage. Token counts are tallied as each
+// fragment is handed off.
+func (p *StreamProcessor) streamEncodeFiles(fsys fs.FS, entries []*walkEntry, analyzer *DependencyAnalyzer, tokenizer Tokenizer, encoder Encoder, sink fragmentSink, stats *Stats) error {
+	parallel := p.parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	jobs := make(chan int)
+	// Each result channel is unbuffered: a worker that finishes index i
+	// blocks until the merge loop (which drains strictly in order) is
+	// ready for it, which caps in-flight encoded buffers at ~parallel.
+	results := make([]chan encodedFile, len(entries))
+	for i := range results {
+		results[i] = make(chan encodedFile)
 	}
 
-	for _, excluded := range excludedPaths {
-		if strings.Contains(path, excluded) {
-			return true
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry := entries[i]
+				content, _ := analyzer.Lookup(entry.relPath)
+				results[i] <- p.encodeEntry(fsys, entry, content, tokenizer, encoder)
+			}
+		}()
+	}
+
+	go func() {
+		for i := range entries {
+			jobs <- i
 		}
+		close(jobs)
+	}()
+	go wg.Wait()
+
+	for i := range entries {
+		res := <-results[i]
+		if res.err != nil {
+			fmt.Printf("Error encoding %s: %v\n", res.relPath, res.err)
+			stats.errors++
+			continue
+		}
+
+		stats.tokens += res.tokens
+		stats.recordFileTokens(res.relPath, res.tokens)
+
+		if err := sink.WriteFile(res.relPath, res.data, res.tokens, res.content); err != nil {
+			return fmt.Errorf("error writing output for %s: %v", res.relPath, err)
+		}
+	}
+
+	return nil
+}
+
+// encodeEntry reads entry's content out of fsys in p.bufferSize chunks,
+// counts its tokens, and renders it (plus any dependency info
+// AnalyzeDependencies attached to content) as a single file fragment in
+// the configured output format. Tokens are counted and assigned to
+// content.Tokens before encoding so the count itself appears in the
+// rendered fragment, not just in Stats.
+func (p *StreamProcessor) encodeEntry(fsys fs.FS, entry *walkEntry, content *FileContent, tokenizer Tokenizer, encoder Encoder) encodedFile {
+	if content == nil {
+		content = &FileContent{Name: entry.relPath, Size: entry.size}
+	}
+
+	file, err := fsys.Open(entry.relPath)
+	if err != nil {
+		return encodedFile{relPath: entry.relPath, err: err}
+	}
+	defer file.Close()
+
+	buf := bytes.NewBuffer(make([]byte, 0, p.bufferSize))
+	reader := bufio.NewReaderSize(file, p.bufferSize)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return encodedFile{relPath: entry.relPath, err: err}
+	}
+	content.Content = buf.String()
+
+	tokens := int64(tokenizer.CountTokens(content.Content))
+	content.Tokens = tokens
+
+	data, err := encoder.EncodeFile(content)
+	if err != nil {
+		return encodedFile{relPath: entry.relPath, err: err}
 	}
 
-	return false
+	return encodedFile{relPath: entry.relPath, data: data, content: content, tokens: tokens}
 }
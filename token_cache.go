@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// tokenCache persists per-file token counts keyed by content hash, so
+// re-running xdir over an unchanged tree doesn't re-run BPE merging for
+// every file. It's scoped per tokenizer name since the same content
+// encodes to different counts under different encodings. Entries
+// accumulate in memory and are only written to disk on Flush, since
+// writing the whole map back out on every single miss would make a
+// fresh run over a large tree (exactly when the cache matters most)
+// quadratic in file count.
+type tokenCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]int
+	dirty   bool
+}
+
+// newTokenCache loads (or lazily creates) the on-disk cache for the named
+// tokenizer. Load failures are treated as an empty cache rather than a
+// hard error, since the cache is purely an optimization.
+func newTokenCache(tokenizerName string) *tokenCache {
+	tc := &tokenCache{entries: make(map[string]int)}
+
+	dir, err := tokenizerCacheDir()
+	if err != nil {
+		return tc
+	}
+	tc.path = filepath.Join(dir, tokenizerName+".cache.json")
+
+	data, err := os.ReadFile(tc.path)
+	if err != nil {
+		return tc
+	}
+	_ = json.Unmarshal(data, &tc.entries)
+
+	return tc
+}
+
+// Get returns the cached token count for hash, if any.
+func (tc *tokenCache) Get(hash string) (int, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	count, ok := tc.entries[hash]
+	return count, ok
+}
+
+// Set records count for hash in memory. It does not write to disk - call
+// Flush once the run is done, or entries will never be persisted.
+func (tc *tokenCache) Set(hash string, count int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.entries[hash] = count
+	tc.dirty = true
+}
+
+// Flush writes the accumulated cache to disk if anything changed since
+// the last flush. Callers should call this once after a run completes,
+// not per entry: marshaling and rewriting the whole map on every miss
+// would make a fresh run over a large tree quadratic in file count.
+// Errors are swallowed; a failed flush just means the next run
+// recomputes those entries.
+func (tc *tokenCache) Flush() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.path == "" || !tc.dirty {
+		return
+	}
+	data, err := json.Marshal(tc.entries)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(tc.path, data, 0o644); err != nil {
+		return
+	}
+	tc.dirty = false
+}
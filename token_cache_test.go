@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTokenCacheSetDoesNotWriteUntilFlush guards against Set rewriting the
+// whole cache file on every miss, which made a fresh run over a large
+// tree pathologically slow.
+func TestTokenCacheSetDoesNotWriteUntilFlush(t *testing.T) {
+	dir := t.TempDir()
+	tc := &tokenCache{path: filepath.Join(dir, "words.cache.json"), entries: make(map[string]int)}
+
+	for i := 0; i < 5; i++ {
+		tc.Set(hashText(string(rune('a'+i))), i)
+		if _, err := os.Stat(tc.path); err == nil {
+			t.Fatalf("Set wrote cache file to disk before Flush was called")
+		}
+	}
+
+	tc.Flush()
+	if _, err := os.Stat(tc.path); err != nil {
+		t.Fatalf("Flush did not write cache file: %v", err)
+	}
+
+	data, err := os.ReadFile(tc.path)
+	if err != nil {
+		t.Fatalf("reading flushed cache: %v", err)
+	}
+	var onDisk map[string]int
+	if err := json.Unmarshal(data, &onDisk); err != nil {
+		t.Fatalf("unmarshaling flushed cache: %v", err)
+	}
+	if len(onDisk) != 5 {
+		t.Fatalf("flushed cache has %d entries, want 5", len(onDisk))
+	}
+}
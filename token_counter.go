@@ -5,35 +5,36 @@ import (
 	"unicode"
 )
 
-// TokenCounter handles token counting for LLM context
-type TokenCounter struct {
-	count int
+// WordTokenizer provides a crude estimation of tokens in text by splitting
+// on whitespace and punctuation. It doesn't match any real model's
+// encoding; prefer a BPE-backed Tokenizer (the default) for accurate
+// counts. Kept around as a fast fallback via -tokenizer=words.
+type WordTokenizer struct{}
+
+// NewWordTokenizer creates a new WordTokenizer.
+func NewWordTokenizer() *WordTokenizer {
+	return &WordTokenizer{}
 }
 
-// NewTokenCounter creates a new TokenCounter
-func NewTokenCounter() *TokenCounter {
-	return &TokenCounter{count: 0}
+// Name identifies this tokenizer, as accepted by -tokenizer.
+func (wt *WordTokenizer) Name() string {
+	return "words"
 }
 
-// CountTokens provides a simple estimation of tokens in text
-// This is a basic implementation that splits on whitespace and punctuation
-// For production use, you would want to use a proper tokenizer matching your LLM
-func (tc *TokenCounter) CountTokens(text string) int {
+// CountTokens splits text into words, then counts each run of punctuation
+// within a word as its own token.
+func (wt *WordTokenizer) CountTokens(text string) int {
 	tokens := 0
 	inToken := false
 
-	// Split into words first
 	words := strings.Fields(text)
 
 	for _, word := range words {
-		// Handle each character in the word
 		for _, char := range word {
 			if unicode.IsPunct(char) {
-				// Count punctuation as separate tokens
 				tokens++
 				inToken = false
 			} else if !inToken {
-				// Start of new token
 				tokens++
 				inToken = true
 			}
@@ -41,11 +42,5 @@ func (tc *TokenCounter) CountTokens(text string) int {
 		inToken = false
 	}
 
-	tc.count += tokens
 	return tokens
 }
-
-// GetTotalTokens returns the total number of tokens counted
-func (tc *TokenCounter) GetTotalTokens() int {
-	return tc.count
-}
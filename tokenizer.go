@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Tokenizer counts tokens for a chunk of text using whatever scheme the
+// backing implementation models. Different backends trade accuracy for
+// speed: BPE backends mirror a specific model family's encoding, while
+// the word tokenizer is a fast, inaccurate fallback.
+type Tokenizer interface {
+	// CountTokens returns the number of tokens text would encode to.
+	CountTokens(text string) int
+	// Name identifies the tokenizer, as accepted by -tokenizer.
+	Name() string
+}
+
+// NewTokenizer selects a Tokenizer implementation by name, as surfaced via
+// -tokenizer on the CLI. An empty name defaults to cl100k_base, the
+// encoding used by GPT-3.5/GPT-4 era models and the most common budgeting
+// target. A BPE encoding needs its merge table from a local cache or a
+// download on first use (see bpe_tokenizer.go); when neither is available
+// - an offline run or a sandboxed CI box - xdir falls back to the word
+// tokenizer rather than hard-failing, since a rough count beats none.
+func NewTokenizer(name string) (Tokenizer, error) {
+	switch name {
+	case "":
+		return newBPEOrFallback("cl100k_base")
+	case "cl100k_base", "o200k_base":
+		return NewBPETokenizer(name)
+	case "anthropic":
+		return NewAnthropicTokenizer(), nil
+	case "words":
+		return NewWordTokenizer(), nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (want cl100k_base, o200k_base, anthropic, or words)", name)
+	}
+}
+
+// tokenizerFlusher is implemented by Tokenizer backends that buffer writes
+// to an on-disk cache (BPETokenizer) and need an explicit flush once a run
+// is done, rather than on every CountTokens call.
+type tokenizerFlusher interface {
+	Flush()
+}
+
+// flushTokenizer flushes tokenizer's on-disk cache, if it has one. Callers
+// invoke this once after a full run (or watch-mode render cycle)
+// completes, not per file.
+func flushTokenizer(tokenizer Tokenizer) {
+	if f, ok := tokenizer.(tokenizerFlusher); ok {
+		f.Flush()
+	}
+}
+
+// newBPEOrFallback builds the default tokenizer, falling back to the word
+// tokenizer (with a warning) if the BPE encoding's merge table can't be
+// loaded, e.g. no network access and nothing cached yet.
+func newBPEOrFallback(encodingName string) (Tokenizer, error) {
+	bt, err := NewBPETokenizer(encodingName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't load %s merge table (%v); falling back to the word tokenizer\n", encodingName, err)
+		return NewWordTokenizer(), nil
+	}
+	return bt, nil
+}
@@ -9,6 +9,7 @@ type FileContent struct {
 	XMLName      xml.Name        `xml:"file"`
 	Name         string          `xml:"name,attr"`
 	Size         int64           `xml:"size,attr"`
+	Tokens       int64           `xml:"tokens,attr,omitempty"`
 	Content      string          `xml:",cdata"`
 	Dependencies *DependencyInfo `xml:"dependencies,omitempty"`
 }
@@ -28,13 +29,25 @@ type ImportDependency struct {
 
 // Config holds the application configuration
 type Config struct {
-	maxFileSize  int64
-	compress     bool
-	globPatterns []string
-	filePatterns []string
-	targetDir    string
-	outputFile   string
-	unsafeMode   bool
+	maxFileSize       int64
+	compress          bool
+	globPatterns      []string
+	filePatterns      []string
+	targetDir         string
+	outputFile        string
+	unsafeMode        bool
+	ignoreFile        string
+	noGitignore       bool
+	tokenizer         string
+	bufferSize        int
+	parallel          int
+	format            string
+	shardMaxTokens    int64
+	allowSplit        bool
+	shardOverlapLines int
+	watch             bool
+	serveAddr         string
+	gitRef            string
 }
 
 // Stats tracks processing statistics
@@ -43,6 +56,35 @@ type Stats struct {
 	bytesProc int64
 	errors    int64
 	tokens    int64 // Added field for token count
+	topFiles  []FileTokenStat
+}
+
+// FileTokenStat records a single file's token count, used to report the
+// top-N largest files by token count after processing.
+type FileTokenStat struct {
+	Name   string
+	Tokens int64
+}
+
+// topFilesLimit bounds how many entries Stats.topFiles keeps.
+const topFilesLimit = 10
+
+// recordFileTokens inserts name/tokens into topFiles in descending order,
+// dropping the smallest entry once the list exceeds topFilesLimit.
+func (s *Stats) recordFileTokens(name string, tokens int64) {
+	i := 0
+	for ; i < len(s.topFiles); i++ {
+		if tokens > s.topFiles[i].Tokens {
+			break
+		}
+	}
+	s.topFiles = append(s.topFiles, FileTokenStat{})
+	copy(s.topFiles[i+1:], s.topFiles[i:])
+	s.topFiles[i] = FileTokenStat{Name: name, Tokens: tokens}
+
+	if len(s.topFiles) > topFilesLimit {
+		s.topFiles = s.topFiles[:topFilesLimit]
+	}
 }
 
 // Default exclusion patterns
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces bursts of filesystem events (an editor's
+// save-as-temp-then-rename dance routinely fires several in a row) into a
+// single re-render.
+const watchDebounce = 200 * time.Millisecond
+
+// fileCacheEntry is a watch cycle's memo of one file's last-seen content
+// hash and rendered fragment, so a file that hasn't changed since the
+// previous cycle doesn't need to be re-read or re-encoded.
+type fileCacheEntry struct {
+	hash    string
+	content *FileContent
+	data    []byte
+	tokens  int64
+}
+
+// WatchRunner keeps xdir alive, re-rendering config.outputFile whenever
+// files under config.targetDir change. Unchanged files are served from a
+// content-hash cache, and also seed the dependency analyzer with their
+// already-parsed Dependencies so its language-analyzer pass only runs
+// against files that actually changed; linkDependencies' ImportedBy pass
+// still runs over the full current file set every render, since it's
+// pure in-memory bookkeeping rather than a re-parse. If config.serveAddr
+// is set, every render is also pushed to subscribers of the SSE
+// endpoint.
+type WatchRunner struct {
+	config Config
+	sp     *StreamProcessor
+
+	mu    sync.Mutex
+	cache map[string]fileCacheEntry
+
+	// renderMu/rendering/pending serialize renderOnce: a debounce fire
+	// that arrives while a render is already in flight sets pending
+	// instead of running concurrently (which would race on wr.sp.ignorer)
+	// or queuing up one render per fire.
+	renderMu  sync.Mutex
+	rendering bool
+	pending   bool
+
+	broadcast *sseBroadcaster
+}
+
+// NewWatchRunner builds a WatchRunner for config. config.targetDir and
+// config.outputFile are resolved the same way the one-shot path resolves
+// them.
+func NewWatchRunner(config Config) *WatchRunner {
+	sp := NewStreamProcessor(config.bufferSize)
+	sp.parallel = config.parallel
+	return &WatchRunner{
+		config:    config,
+		sp:        sp,
+		cache:     make(map[string]fileCacheEntry),
+		broadcast: newSSEBroadcaster(),
+	}
+}
+
+// Run watches config.targetDir until the process is interrupted,
+// rewriting config.outputFile on every debounced batch of changes. It
+// renders once immediately so a freshly started watch has output right
+// away.
+func (wr *WatchRunner) Run() error {
+	absTargetDir, err := filepath.Abs(wr.config.targetDir)
+	if err != nil {
+		return fmt.Errorf("error resolving target directory: %v", err)
+	}
+	fsys := os.DirFS(absTargetDir)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error starting file watcher: %v", err)
+	}
+	defer watcher.Close()
+
+	ignorer, err := NewIgnorer(fsys, wr.config.ignoreFile, wr.config.noGitignore)
+	if err != nil {
+		return fmt.Errorf("error setting up ignore rules: %v", err)
+	}
+	if err := addWatchDirs(watcher, absTargetDir, wr.config, ignorer); err != nil {
+		return fmt.Errorf("error watching %s: %v", absTargetDir, err)
+	}
+
+	if wr.config.serveAddr != "" {
+		go wr.serve(wr.config.serveAddr)
+	}
+
+	wr.triggerRender()
+
+	var (
+		mu      sync.Mutex
+		changed = make(map[string]struct{})
+		timer   *time.Timer
+	)
+
+	fire := func() {
+		mu.Lock()
+		n := len(changed)
+		changed = make(map[string]struct{})
+		mu.Unlock()
+		if n == 0 {
+			return
+		}
+		fmt.Printf("Detected changes in %d path(s), re-rendering...\n", n)
+		wr.triggerRender()
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if info, statErr := os.Stat(event.Name); statErr == nil && info.IsDir() && event.Op&fsnotify.Create != 0 {
+				if relPath, relErr := relSlashPath(absTargetDir, event.Name); relErr == nil && !isExcludedPath(relPath, true, wr.config, ignorer) {
+					watcher.Add(event.Name)
+				}
+			}
+
+			mu.Lock()
+			changed[event.Name] = struct{}{}
+			mu.Unlock()
+
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(watchDebounce, fire)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Printf("Watch error: %v\n", err)
+		}
+	}
+}
+
+// addWatchDirs registers absDir and every non-excluded subdirectory with
+// watcher. fsnotify watches directories rather than individual files, so
+// new files appearing in an already-watched directory are picked up for
+// free; new directories are added as they're created (see Run above).
+func addWatchDirs(watcher *fsnotify.Watcher, absDir string, config Config, ignorer *Ignorer) error {
+	return filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		relPath, err := relSlashPath(absDir, path)
+		if err != nil {
+			return nil
+		}
+		if err := ignorer.Enter(relPath); err != nil {
+			return nil
+		}
+		if isExcludedPath(relPath, true, config, ignorer) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// relSlashPath returns path's location relative to base as an fs.FS-style
+// slash path ("." for base itself), bridging fsnotify's absolute OS paths
+// to the relPath space Ignorer/isExcludedPath/collectEntries operate in.
+func relSlashPath(base, path string) (string, error) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// triggerRender runs renderOnce, coalescing any fire that arrives while a
+// render is already in flight into a single extra render once the
+// current one finishes, rather than running the two concurrently or
+// queuing one render per fire.
+func (wr *WatchRunner) triggerRender() {
+	wr.renderMu.Lock()
+	if wr.rendering {
+		wr.pending = true
+		wr.renderMu.Unlock()
+		return
+	}
+	wr.rendering = true
+	wr.renderMu.Unlock()
+
+	for {
+		if err := wr.renderOnce(); err != nil {
+			fmt.Printf("Error rendering: %v\n", err)
+		}
+
+		wr.renderMu.Lock()
+		if wr.pending {
+			wr.pending = false
+			wr.renderMu.Unlock()
+			continue
+		}
+		wr.rendering = false
+		wr.renderMu.Unlock()
+		return
+	}
+}
+
+// renderOnce re-walks config.targetDir, reusing cached fragments for any
+// file whose content hash matches the previous cycle, re-encodes the
+// rest, and atomically rewrites config.outputFile. Dependency resolution
+// (and therefore ImportedBy) always runs over the full current file set,
+// since AnalyzeDependencies' linkDependencies pass is what keeps a
+// changed file's reverse dependents correct.
+func (wr *WatchRunner) renderOnce() error {
+	config := wr.config
+	absTargetDir, err := filepath.Abs(config.targetDir)
+	if err != nil {
+		return err
+	}
+	fsys := os.DirFS(absTargetDir)
+
+	ignorer, err := NewIgnorer(fsys, config.ignoreFile, config.noGitignore)
+	if err != nil {
+		return err
+	}
+	wr.sp.ignorer = ignorer
+
+	tokenizer, err := NewTokenizer(config.tokenizer)
+	if err != nil {
+		return err
+	}
+	encoder, err := NewEncoder(config.format)
+	if err != nil {
+		return err
+	}
+
+	analyzer := NewDependencyAnalyzer(fsys)
+
+	var stats Stats
+	entries, err := wr.sp.collectEntries(fsys, config, analyzer, &stats)
+	if err != nil {
+		return err
+	}
+
+	wr.mu.Lock()
+	// Read every survivor once up front so we know which are unchanged
+	// since the last render, and seed the analyzer with those files'
+	// already-parsed content before AnalyzeDependencies runs: its
+	// analyzeFile pass skips any file that already has Dependencies
+	// attached, so only touched files get re-opened and re-scanned for
+	// imports.
+	type readResult struct {
+		hash string
+		raw  []byte
+	}
+	reads := make(map[string]readResult, len(entries))
+	for _, entry := range entries {
+		hash, raw, err := hashFileContent(fsys, entry.relPath)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", entry.relPath, err)
+			stats.errors++
+			continue
+		}
+		reads[entry.relPath] = readResult{hash: hash, raw: raw}
+
+		if cached, ok := wr.cache[entry.relPath]; ok && cached.hash == hash {
+			analyzer.RegisterFile(entry.relPath, cached.content)
+		}
+	}
+
+	if err := analyzer.AnalyzeDependencies(); err != nil {
+		fmt.Printf("Error analyzing dependencies: %v\n", err)
+		stats.errors++
+	}
+
+	var buf bytes.Buffer
+	sink := &singleFileSink{w: &buf}
+	if err := sink.WriteHeader(encoder); err != nil {
+		wr.mu.Unlock()
+		return err
+	}
+
+	nextCache := make(map[string]fileCacheEntry, len(entries))
+	reused := 0
+	for _, entry := range entries {
+		read, ok := reads[entry.relPath]
+		if !ok {
+			continue // read error already counted above
+		}
+		hash, raw := read.hash, read.raw
+
+		content, _ := analyzer.Lookup(entry.relPath)
+		cached, isCached := wr.cache[entry.relPath]
+
+		var data []byte
+		var tokens int64
+		if isCached && cached.hash == hash {
+			data, content, tokens = cached.data, cached.content, cached.tokens
+			reused++
+		} else {
+			if content == nil {
+				content = &FileContent{Name: entry.relPath, Size: entry.size}
+			}
+			content.Content = string(raw)
+			tokens = int64(tokenizer.CountTokens(string(raw)))
+			content.Tokens = tokens
+
+			data, err = encoder.EncodeFile(content)
+			if err != nil {
+				fmt.Printf("Error encoding %s: %v\n", entry.relPath, err)
+				stats.errors++
+				continue
+			}
+		}
+
+		nextCache[entry.relPath] = fileCacheEntry{hash: hash, content: content, data: data, tokens: tokens}
+		stats.tokens += tokens
+		stats.recordFileTokens(entry.relPath, tokens)
+
+		if err := sink.WriteFile(entry.relPath, data, tokens, content); err != nil {
+			wr.mu.Unlock()
+			return err
+		}
+	}
+	wr.cache = nextCache
+	wr.mu.Unlock()
+
+	flushTokenizer(tokenizer)
+
+	if err := sink.WriteFooter(encoder); err != nil {
+		return err
+	}
+
+	if err := atomicWriteFile(config.outputFile, buf.Bytes()); err != nil {
+		return err
+	}
+
+	wr.broadcast.publish(buf.Bytes())
+	fmt.Printf("Rewrote %s (%d files, %d reused, %d tokens)\n", config.outputFile, len(entries), reused, stats.tokens)
+	return nil
+}
+
+// hashFileContent reads relPath out of fsys and returns its content
+// alongside a stable hash of it, reusing the same digest hashText uses
+// for the tokenizer cache so a file's "has this changed" key is computed
+// the same way everywhere in xdir.
+func hashFileContent(fsys fs.FS, relPath string) (string, []byte, error) {
+	data, err := fs.ReadFile(fsys, relPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return hashText(string(data)), data, nil
+}
+
+// atomicWriteFile writes data to a temp file in the same directory as
+// path and renames it into place, so a subscriber reading path never
+// observes a partially written render.
+func atomicWriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, ".xdir-watch-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// sseBroadcaster fans out each render to every currently connected
+// Server-Sent Events subscriber opened via WatchRunner.serve. Renders
+// that happen with no subscribers are simply dropped, same as a buffered
+// channel send with nobody listening would be.
+type sseBroadcaster struct {
+	mu   sync.Mutex
+	subs map[chan []byte]struct{}
+}
+
+func newSSEBroadcaster() *sseBroadcaster {
+	return &sseBroadcaster{subs: make(map[chan []byte]struct{})}
+}
+
+func (b *sseBroadcaster) subscribe() chan []byte {
+	ch := make(chan []byte, 1)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *sseBroadcaster) unsubscribe(ch chan []byte) {
+	b.mu.Lock()
+	delete(b.subs, ch)
+	b.mu.Unlock()
+}
+
+func (b *sseBroadcaster) publish(data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- data:
+		default:
+			// Subscriber is behind; drop this render rather than block the
+			// watch loop, the next one will catch it up.
+		}
+	}
+}
+
+// serve starts the -serve HTTP endpoint: GET / streams the current
+// rendered output as Server-Sent Events, sending one "message" event per
+// render (starting with whatever was last rendered, if anything) so an
+// editor plugin or agent loop can subscribe instead of polling the CLI.
+func (wr *WatchRunner) serve(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		ch := wr.broadcast.subscribe()
+		defer wr.broadcast.unsubscribe(ch)
+
+		if data, err := os.ReadFile(wr.config.outputFile); err == nil {
+			writeSSEEvent(w, data)
+			flusher.Flush()
+		}
+
+		for {
+			select {
+			case data := <-ch:
+				writeSSEEvent(w, data)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	fmt.Printf("Serving live output on http://%s (SSE)\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Printf("Error serving -serve=%s: %v\n", addr, err)
+	}
+}
+
+// writeSSEEvent writes data as a single SSE "message" event, escaping
+// embedded newlines across multiple "data:" lines per the SSE wire
+// format.
+func writeSSEEvent(w http.ResponseWriter, data []byte) {
+	fmt.Fprint(w, "event: message\n")
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestWatchRunner(dir, outputFile string) *WatchRunner {
+	return NewWatchRunner(Config{
+		targetDir:  dir,
+		outputFile: outputFile,
+		tokenizer:  "words",
+		format:     "xml",
+		bufferSize: 16,
+		parallel:   1,
+	})
+}
+
+func TestRenderOnceReusesUnchangedFilesOnSecondRender(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0o644); err != nil {
+		t.Fatalf("write a.go: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte("package b"), 0o644); err != nil {
+		t.Fatalf("write b.go: %v", err)
+	}
+
+	wr := newTestWatchRunner(dir, filepath.Join(dir, "out.xml"))
+
+	if err := wr.renderOnce(); err != nil {
+		t.Fatalf("first renderOnce: %v", err)
+	}
+	wr.mu.Lock()
+	firstCacheLen := len(wr.cache)
+	wr.mu.Unlock()
+	if firstCacheLen != 2 {
+		t.Fatalf("cache has %d entries after first render, want 2", firstCacheLen)
+	}
+
+	if err := wr.renderOnce(); err != nil {
+		t.Fatalf("second renderOnce: %v", err)
+	}
+
+	wr.mu.Lock()
+	aEntry, ok := wr.cache["a.go"]
+	wr.mu.Unlock()
+	if !ok {
+		t.Fatalf("expected a.go still cached after second render")
+	}
+	if aEntry.data == nil {
+		t.Fatalf("expected cached fragment for unchanged a.go")
+	}
+}
+
+func TestRenderOnceRecomputesChangedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a"), 0o644); err != nil {
+		t.Fatalf("write a.go: %v", err)
+	}
+
+	wr := newTestWatchRunner(dir, filepath.Join(dir, "out.xml"))
+	if err := wr.renderOnce(); err != nil {
+		t.Fatalf("first renderOnce: %v", err)
+	}
+	wr.mu.Lock()
+	firstHash := wr.cache["a.go"].hash
+	wr.mu.Unlock()
+
+	if err := os.WriteFile(path, []byte("package a // changed"), 0o644); err != nil {
+		t.Fatalf("rewrite a.go: %v", err)
+	}
+	if err := wr.renderOnce(); err != nil {
+		t.Fatalf("second renderOnce: %v", err)
+	}
+
+	wr.mu.Lock()
+	secondHash := wr.cache["a.go"].hash
+	wr.mu.Unlock()
+	if secondHash == firstHash {
+		t.Fatalf("expected cache hash to change after editing a.go")
+	}
+}
+
+func TestRenderOnceExcludesOwnOutputFileAcrossRepeatedRenders(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0o644); err != nil {
+		t.Fatalf("write a.go: %v", err)
+	}
+	outputFile := filepath.Join(dir, "out.xml")
+	wr := newTestWatchRunner(dir, outputFile)
+
+	var tokens []int64
+	for i := 0; i < 4; i++ {
+		if err := wr.renderOnce(); err != nil {
+			t.Fatalf("renderOnce #%d: %v", i, err)
+		}
+		wr.mu.Lock()
+		if _, ok := wr.cache["out.xml"]; ok {
+			wr.mu.Unlock()
+			t.Fatalf("render #%d re-ingested its own output file into the cache", i)
+		}
+		tokens = append(tokens, wr.cache["a.go"].tokens)
+		wr.mu.Unlock()
+	}
+
+	for i, tok := range tokens {
+		if tok != tokens[0] {
+			t.Fatalf("token counts grew across renders (own output re-ingested?): %v (render #%d = %d, want %d)", tokens, i, tok, tokens[0])
+		}
+	}
+}
+
+func TestTriggerRenderCoalescesOverlappingFires(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0o644); err != nil {
+		t.Fatalf("write a.go: %v", err)
+	}
+	wr := newTestWatchRunner(dir, filepath.Join(dir, "out.xml"))
+
+	done := make(chan struct{}, 2)
+	go func() { wr.triggerRender(); done <- struct{}{} }()
+	go func() { wr.triggerRender(); done <- struct{}{} }()
+	<-done
+	<-done
+
+	if _, err := os.Stat(filepath.Join(dir, "out.xml")); err != nil {
+		t.Fatalf("expected output file to exist after concurrent triggerRender calls: %v", err)
+	}
+}